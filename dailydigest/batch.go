@@ -0,0 +1,420 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// deliveryResult records the outcome of one zone's digest so failed zones can
+// be picked out and retried, even when they were sent as part of a batch.
+type deliveryResult struct {
+	Zone  string
+	CType CType
+	Err   error
+}
+
+// occurrence is one digest that fired: the zone, the abbreviation group it
+// was found under, and the schedule that matched. Due is the local time it
+// fires for, DeferredFrom the local time it was originally due when a quiet
+// window held it back, and ShiftedFrom the day it was due when a holiday
+// moved it. Period is what it covers.
+type occurrence struct {
+	Group        string
+	Zone         string
+	Schedule     schedule
+	Due          time.Time
+	DeferredFrom time.Time
+	ShiftedFrom  time.Time
+	Period       digestPeriod
+}
+
+func (o occurrence) cType() CType {
+	return o.Schedule.CType
+}
+
+// Fan-out modes. With fanOutOffset the zones that fire together are grouped
+// by their current UTC offset, so the endpoint gets one trigger per offset.
+const (
+	fanOutZone   = "zone"
+	fanOutOffset = "offset"
+)
+
+// batchKey groups occurrences that can share one batch request. In offset
+// fan-out mode only Offset, CType and LocalTime are set; what differs between
+// the zones on an offset goes in the payload's zone_periods.
+type batchKey struct {
+	Offset      string
+	CType       CType
+	LocalTime   string
+	Deferred    bool
+	Shifted     bool
+	Period      string
+	PeriodStart string
+	PeriodEnd   string
+	ISOWeek     string
+	Fiscal      fiscalID
+}
+
+// triggerKey identifies one digest for a zone. The same location can sit
+// under more than one abbreviation group, and fires once.
+type triggerKey struct {
+	Zone      string
+	CType     CType
+	LocalTime string
+}
+
+// digestRun collects what fired for one tenant during a single invocation.
+// Outside batch mode every trigger is posted right away; in batch mode zones
+// are queued per CType and sent on flush. The sinks, metrics and report are
+// shared by every tenant's run. In offset fan-out mode batches are keyed by
+// UTC offset as well. A dry run only records what would be sent in plan.
+type digestRun struct {
+	dryRun    bool
+	plan      *dryRunPlan
+	at        time.Time
+	tenant    *tenant
+	sender    *sender
+	dead      deadLetterSink
+	store     stateStore
+	batch     bool
+	batchSize int
+	byOffset  bool
+	pending   map[batchKey][]occurrence
+	order     []batchKey
+	results   []deliveryResult
+	groups    map[string]string
+	fired     map[triggerKey]bool
+	metrics   *runMetrics
+	report    *runReport
+	subs      *subscriptionSet
+}
+
+// newDigestRun sets up what the tenants of one invocation share. Use
+// forTenant to get the run that triggers are sent through.
+func newDigestRun(ctx context.Context, c config) *digestRun {
+	dead, err := newDeadLetterSink(ctx, c)
+	if err != nil {
+		slog.Error("dead-letter sink unavailable, failed digests will be lost", "error", err)
+	}
+	store, err := newStateStore(ctx, c)
+	if err != nil {
+		slog.Error("state store unavailable, circuit breaker state stays local", "error", err)
+	}
+	if c.FanOut != fanOutZone && c.FanOut != fanOutOffset {
+		slog.Error("unknown fan-out mode, triggering per zone", "fanout", c.FanOut)
+	}
+	return &digestRun{
+		sender:    newSender(c),
+		dead:      dead,
+		store:     store,
+		batch:     c.BatchMode,
+		batchSize: c.BatchSize,
+		byOffset:  c.FanOut == fanOutOffset,
+		metrics:   newRunMetrics(c),
+	}
+}
+
+// forTenant returns a run for tn with its own pending batches and results.
+func (r *digestRun) forTenant(ctx context.Context, tn *tenant) *digestRun {
+	if !r.dryRun {
+		loadBreaker(ctx, r.store, breakerFor(r.sender.cfg, tn.Endpoint), tn.Endpoint)
+	}
+	return &digestRun{
+		dryRun:    r.dryRun,
+		plan:      r.plan,
+		at:        r.at,
+		tenant:    tn,
+		sender:    r.sender,
+		dead:      r.dead,
+		store:     r.store,
+		batch:     r.batch,
+		batchSize: r.batchSize,
+		byOffset:  r.byOffset,
+		pending:   map[batchKey][]occurrence{},
+		groups:    map[string]string{},
+		fired:     map[triggerKey]bool{},
+		metrics:   r.metrics,
+		report:    r.report,
+		subs:      r.subs,
+	}
+}
+
+// trigger sends or queues the digest for one occurrence.
+func (r *digestRun) trigger(ctx context.Context, occ occurrence) {
+	tk := triggerKey{occ.Zone, occ.cType(), occ.Schedule.localTime()}
+	if r.fired[tk] {
+		return
+	}
+	r.fired[tk] = true
+	if _, seen := r.groups[occ.Zone]; !seen {
+		r.groups[occ.Zone] = occ.Group
+	}
+	if r.dryRun {
+		r.plan.Triggers = append(r.plan.Triggers, r.planned(occ))
+		return
+	}
+	r.metrics.count(occ.cType(), occ.Group, "triggered")
+	if !r.batch && !r.byOffset {
+		_, err := r.deliver(ctx, []string{occ.Zone}, occ.cType(), zonePayload(r.tenant, occ))
+		r.record(occ.Zone, occ.cType(), err)
+		return
+	}
+	key := batchKey{CType: occ.cType(), LocalTime: occ.Schedule.localTime()}
+	if r.byOffset {
+		key.Offset = occ.Due.Format("-07:00")
+	} else {
+		key.Deferred, key.Shifted = !occ.DeferredFrom.IsZero(), !occ.ShiftedFrom.IsZero()
+		key.Period, key.ISOWeek, key.Fiscal = occ.Period.Kind, occ.Period.isoWeek(), occ.Period.Fiscal
+		key.PeriodStart, key.PeriodEnd = occ.Period.dates()
+	}
+	if _, ok := r.pending[key]; !ok {
+		r.order = append(r.order, key)
+	}
+	r.pending[key] = append(r.pending[key], occ)
+}
+
+// flush sends every queued batch and saves the breaker state for the next
+// invocation. An offset group is always sent whole.
+func (r *digestRun) flush(ctx context.Context) {
+	if r.dryRun {
+		return
+	}
+	defer saveBreaker(ctx, r.store, breakerFor(r.sender.cfg, r.tenant.Endpoint), r.tenant.Endpoint)
+	for _, key := range r.order {
+		size := r.batchSize
+		if key.Offset != "" {
+			size = 0
+		}
+		for _, occs := range chunkOccurrences(r.pending[key], size) {
+			slog.Info("triggered batch", "tenant", r.tenant.ID, "ctype", key.CType, "local_time", key.LocalTime, "offset", key.Offset, "zones", len(occs), "outcome", "triggered")
+			bctx, span := tracer.Start(ctx, "digest.batch", trace.WithAttributes(
+				attribute.String("digest.tenant", r.tenant.ID),
+				attribute.String("digest.ctype", string(key.CType)),
+				attribute.Int("digest.zones", len(occs)),
+			))
+			failed, err := r.postBatch(bctx, key, occs)
+			endSpan(span, err)
+			for _, o := range occs {
+				if err != nil {
+					r.record(o.Zone, key.CType, err)
+				} else {
+					r.record(o.Zone, key.CType, failed[o.Zone])
+				}
+			}
+		}
+	}
+	r.pending = map[batchKey][]occurrence{}
+	r.order = nil
+}
+
+func (r *digestRun) record(zone string, cType CType, err error) {
+	outcome := "delivered"
+	if err != nil {
+		outcome = "failed"
+	}
+	if r.report != nil {
+		r.report.Results = append(r.report.Results, reportResult{
+			Tenant: r.tenant.ID, Zone: zone, Group: r.groups[zone], CType: cType, Outcome: outcome, Error: errString(err),
+		})
+	}
+	if err != nil {
+		slog.Warn("digest failed", "tenant", r.tenant.ID, "zone", zone, "ctype", cType, "error", err, "outcome", "failed")
+		r.metrics.count(cType, r.groups[zone], "failed")
+	} else {
+		slog.Info("digest delivered", "tenant", r.tenant.ID, "zone", zone, "ctype", cType, "outcome", "delivered")
+		r.metrics.count(cType, r.groups[zone], "delivered")
+	}
+	r.results = append(r.results, deliveryResult{Zone: zone, CType: cType, Err: err})
+}
+
+// suppress records an occurrence that a quiet window kept from firing.
+func (r *digestRun) suppress(occ occurrence, s *suppression) {
+	p := r.planned(occ)
+	p.DueAt = s.Occurrence.Format(time.RFC3339)
+	p.Reason, p.Policy, p.Window, p.Holiday = s.Reason, s.Policy, s.Window, s.Holiday
+	if r.dryRun {
+		r.plan.Suppressed = append(r.plan.Suppressed, p)
+		return
+	}
+	slog.Info("suppressed", "tenant", r.tenant.ID, "group", occ.Group, "zone", occ.Zone, "ctype", occ.cType(), "reason", p.Reason, "policy", p.Policy, "window", p.Window, "holiday", p.Holiday, "outcome", "suppressed")
+	r.metrics.count(occ.cType(), occ.Group, "suppressed")
+	if r.report != nil {
+		r.report.Suppressed = append(r.report.Suppressed, p)
+	}
+}
+
+func (r *digestRun) planned(occ occurrence) plannedDigest {
+	p := plannedDigest{
+		Tenant:      r.tenant.ID,
+		EvaluatedAt: r.at,
+		Zone:        occ.Zone,
+		Group:       occ.Group,
+		CType:       occ.cType(),
+		LocalTime:   occ.Schedule.localTime(),
+	}
+	if r.byOffset && !occ.Due.IsZero() {
+		p.Offset = occ.Due.Format("-07:00")
+	}
+	if !occ.DeferredFrom.IsZero() {
+		p.DeferredFrom = occ.DeferredFrom.Format(time.RFC3339)
+	}
+	if !occ.ShiftedFrom.IsZero() {
+		p.ShiftedFrom = occ.ShiftedFrom.Format(time.DateOnly)
+	}
+	return p
+}
+
+// failed returns the results that did not reach the app endpoint.
+func (r *digestRun) failed() []deliveryResult {
+	var out []deliveryResult
+	for _, res := range r.results {
+		if res.Err != nil {
+			out = append(out, res)
+		}
+	}
+	return out
+}
+
+func chunkOccurrences(occs []occurrence, size int) [][]occurrence {
+	if size <= 0 || size >= len(occs) {
+		if len(occs) == 0 {
+			return nil
+		}
+		return [][]occurrence{occs}
+	}
+	var chunks [][]occurrence
+	for size < len(occs) {
+		occs, chunks = occs[size:], append(chunks, occs[:size])
+	}
+	return append(chunks, occs)
+}
+
+// deliver sends payload with retries and dead-letters it when every attempt
+// failed.
+func (r *digestRun) deliver(ctx context.Context, zones []string, cType CType, payload interface{}) ([]byte, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	respBody, attempts, err := r.sender.send(ctx, r.tenant, body)
+	if r.report != nil {
+		r.report.Deliveries = append(r.report.Deliveries, reportDelivery{
+			Tenant: r.tenant.ID, Zones: zones, CType: cType, Payload: body, Attempts: attempts,
+			LatencyMs: time.Since(start).Milliseconds(), Error: errString(err),
+		})
+	}
+	if err != nil {
+		r.metrics.delivery(cType, "failed", time.Since(start), attempts)
+		r.deadLetter(ctx, zones, cType, body, attempts, err)
+	} else {
+		r.metrics.delivery(cType, "delivered", time.Since(start), attempts)
+	}
+	return respBody, err
+}
+
+func (r *digestRun) deadLetter(ctx context.Context, zones []string, cType CType, body []byte, attempts int, cause error) {
+	if r.dead == nil {
+		return
+	}
+	dl := deadLetter{
+		Tenant:    r.tenant.ID,
+		Target:    r.tenant.Endpoint,
+		CType:     cType,
+		Zones:     zones,
+		Payload:   body,
+		Attempts:  attempts,
+		LastError: cause.Error(),
+		FailedAt:  time.Now().UTC(),
+	}
+	if err := r.dead.Put(ctx, dl); err != nil {
+		slog.Error("cannot dead-letter digest", "tenant", r.tenant.ID, "ctype", cType, "zones", zones, "error", err, "outcome", "lost")
+		return
+	}
+	slog.Warn("digest dead-lettered", "tenant", r.tenant.ID, "ctype", cType, "zones", zones, "attempts", attempts, "error", cause, "outcome", "dead_lettered")
+}
+
+// postBatch sends one request for zones. The endpoint may answer with
+// {"failed": {"<zone>": "<reason>"}} to reject individual zones; those come
+// back as per-zone errors, and are dead-lettered on their own, while the rest
+// of the batch counts as delivered.
+func (r *digestRun) postBatch(ctx context.Context, key batchKey, occs []occurrence) (map[string]error, error) {
+	zones := make([]string, len(occs))
+	for i, o := range occs {
+		zones[i] = o.Zone
+	}
+	cType := key.CType
+	payload := map[string]interface{}{
+		"zones":      zones,
+		"type":       string(cType),
+		"local_time": key.LocalTime,
+	}
+	labels := map[string]interface{}{}
+	for _, o := range occs {
+		labels[o.Zone] = r.tenant.labels(o)
+	}
+	payload["zone_labels"] = labels
+	if key.Offset != "" {
+		payload["offset"] = key.Offset
+		periods := map[string]interface{}{}
+		for _, o := range occs {
+			periods[o.Zone] = zoneDetails(o)
+		}
+		payload["zone_periods"] = periods
+	} else {
+		payload["deferred"], payload["shifted"] = key.Deferred, key.Shifted
+	}
+	if key.Offset == "" && key.Period != "" {
+		periods := map[string]interface{}{}
+		for _, o := range occs {
+			periods[o.Zone] = o.Period.boundaries(map[string]interface{}{})
+		}
+		payload["period"], payload["iso_week"] = key.Period, key.ISOWeek
+		payload["period_start"], payload["period_end"] = key.PeriodStart, key.PeriodEnd
+		payload["zone_periods"] = periods
+		key.Fiscal.payload(payload)
+	}
+	respBody, err := r.deliver(ctx, zones, cType, payload)
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Failed map[string]string `json:"failed"`
+	}
+	failed := map[string]error{}
+	if len(respBody) == 0 || json.Unmarshal(respBody, &resp) != nil {
+		return failed, nil
+	}
+	for _, o := range occs {
+		reason, ok := resp.Failed[o.Zone]
+		if !ok {
+			continue
+		}
+		failed[o.Zone] = fmt.Errorf("rejected by endpoint: %s", reason)
+		r.deadLetter(ctx, []string{o.Zone}, cType, mustJSON(zonePayload(r.tenant, o)), 1, failed[o.Zone])
+	}
+	return failed, nil
+}
+
+// zoneDetails is what an offset trigger says about one of its zones: whether
+// it was deferred or shifted, and the period it covers.
+func zoneDetails(o occurrence) map[string]interface{} {
+	d := map[string]interface{}{
+		"deferred": !o.DeferredFrom.IsZero(),
+		"shifted":  !o.ShiftedFrom.IsZero(),
+	}
+	if !o.DeferredFrom.IsZero() {
+		d["deferred_from"] = o.DeferredFrom.Format(time.RFC3339)
+	}
+	if !o.ShiftedFrom.IsZero() {
+		d["shifted_from"] = o.ShiftedFrom.Format(time.DateOnly)
+	}
+	o.Period.payload(d)
+	return d
+}
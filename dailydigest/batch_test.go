@@ -0,0 +1,202 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// testRun returns the default tenant's run against endpoint, with the
+// configuration edit applied.
+func testRun(t *testing.T, endpoint string, edit func(c *config)) *digestRun {
+	t.Helper()
+	useConfig(t, func(c *config) {
+		c.Endpoint = endpoint
+		if edit != nil {
+			edit(c)
+		}
+	})
+	tenants, err := loadTenants(t.Context(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := newDigestRun(t.Context(), cfg)
+	base.at = time.Date(2026, time.March, 2, 15, 5, 0, 0, time.UTC)
+	return base.forTenant(t.Context(), tenants[0])
+}
+
+// dailyAt returns the daily digest occurrence for zone, found under group,
+// at the UTC instant at.
+func dailyAt(t *testing.T, group, zone string, at time.Time) occurrence {
+	t.Helper()
+	loc, err := loadLocation(zone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return occurrence{Group: group, Zone: zone, Schedule: dailySchedule, Due: at.In(loc)}
+}
+
+func failedZones(r *digestRun) []string {
+	var out []string
+	for _, res := range r.failed() {
+		out = append(out, res.Zone)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func TestBatchPartialFailure(t *testing.T) {
+	at := time.Date(2026, time.March, 2, 15, 5, 0, 0, time.UTC)
+	zones := []string{"Europe/Paris", "Europe/Berlin", "Europe/Rome", "Europe/Madrid", "Europe/Vienna"}
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		size     int
+		requests int
+		failed   []string
+	}{
+		{"all delivered", http.StatusOK, `{}`, 0, 1, nil},
+		{"empty body", http.StatusOK, ``, 0, 1, nil},
+		{"not json", http.StatusOK, `ok`, 0, 1, nil},
+		{"zones rejected", http.StatusOK, `{"failed": {"Europe/Paris": "no workspace", "Europe/Rome": "paused"}}`, 0, 1,
+			[]string{"Europe/Paris", "Europe/Rome"}},
+		{"unknown zone rejected", http.StatusOK, `{"failed": {"Asia/Tokyo": "not sent"}}`, 0, 1, nil},
+		{"whole batch fails", http.StatusBadRequest, `{}`, 0, 1, zones},
+		{"chunked", http.StatusOK, `{}`, 2, 3, nil},
+		{"chunked with rejection", http.StatusOK, `{"failed": {"Europe/Vienna": "paused"}}`, 2, 3, []string{"Europe/Vienna"}},
+		{"size above zone count", http.StatusOK, `{}`, 10, 1, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newDigestServer(t, func(map[string]interface{}) (int, string) { return tt.status, tt.body })
+			run := testRun(t, srv.URL, func(c *config) { c.BatchMode, c.BatchSize = true, tt.size })
+			for _, z := range zones {
+				run.trigger(t.Context(), dailyAt(t, "CET", z, at))
+			}
+			run.flush(t.Context())
+			if len(srv.payloads) != tt.requests {
+				t.Errorf("requests = %d, want %d", len(srv.payloads), tt.requests)
+			}
+			var sent []string
+			for _, p := range srv.payloads {
+				batch := p["zones"].([]interface{})
+				if tt.size > 0 && len(batch) > tt.size {
+					t.Errorf("batch of %d zones, size %d", len(batch), tt.size)
+				}
+				for _, z := range batch {
+					sent = append(sent, z.(string))
+				}
+			}
+			if len(sent) != len(zones) {
+				t.Errorf("sent zones = %v, want each of %v once", sent, zones)
+			}
+			if got := failedZones(run); !reflect.DeepEqual(got, sortedCopy(tt.failed)) {
+				t.Errorf("failed = %v, want %v", got, tt.failed)
+			}
+			if len(run.results) != len(zones) {
+				t.Errorf("results = %d, want one per zone", len(run.results))
+			}
+		})
+	}
+}
+
+func TestTriggerDedupesZonesAcrossGroups(t *testing.T) {
+	at := time.Date(2026, time.March, 2, 16, 5, 0, 0, time.UTC)
+	for _, batch := range []bool{false, true} {
+		srv := newDigestServer(t, nil)
+		run := testRun(t, srv.URL, func(c *config) { c.BatchMode = batch })
+		// Africa/Accra is listed under both GMT and GHST.
+		run.trigger(t.Context(), dailyAt(t, "GMT", "Africa/Accra", at))
+		run.trigger(t.Context(), dailyAt(t, "GHST", "Africa/Accra", at))
+		run.trigger(t.Context(), dailyAt(t, "GMT", "Africa/Abidjan", at))
+		run.flush(t.Context())
+		posted := 0
+		for _, p := range srv.payloads {
+			if zs, ok := p["zones"].([]interface{}); ok {
+				posted += len(zs)
+			} else {
+				posted++
+			}
+		}
+		if posted != 2 || len(run.results) != 2 {
+			t.Errorf("batch %v: posted %d zones with %d results, want 2", batch, posted, len(run.results))
+		}
+		if run.groups["Africa/Accra"] != "GMT" {
+			t.Errorf("batch %v: Africa/Accra reported under %q, want the first group", batch, run.groups["Africa/Accra"])
+		}
+	}
+}
+
+func TestChunkOccurrences(t *testing.T) {
+	occs := make([]occurrence, 5)
+	for _, tt := range []struct {
+		size int
+		want []int
+	}{
+		{0, []int{5}}, {-1, []int{5}}, {1, []int{1, 1, 1, 1, 1}}, {2, []int{2, 2, 1}}, {5, []int{5}}, {6, []int{5}},
+	} {
+		var got []int
+		for _, c := range chunkOccurrences(occs, tt.size) {
+			got = append(got, len(c))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("chunk sizes with %d = %v, want %v", tt.size, got, tt.want)
+		}
+	}
+	if got := chunkOccurrences(nil, 2); got != nil {
+		t.Errorf("chunks of nothing = %v", got)
+	}
+}
+
+func sortedCopy(s []string) []string {
+	if s == nil {
+		return nil
+	}
+	out := append([]string(nil), s...)
+	sort.Strings(out)
+	return out
+}
+
+func TestOffsetFanOutSharesOneTrigger(t *testing.T) {
+	at := time.Date(2026, time.March, 2, 10, 35, 0, 0, time.UTC)
+	srv := newDigestServer(t, nil)
+	run := testRun(t, srv.URL, func(c *config) { c.FanOut, c.BatchSize = fanOutOffset, 1 })
+	var occs []occurrence
+	for _, z := range []string{"Asia/Kolkata", "Asia/Colombo", "Asia/Calcutta"} {
+		occ := dailyAt(t, "IST", z, at)
+		occ.Period = run.tenant.periodFor(occ)
+		occs = append(occs, occ)
+	}
+	// Colombo's digest was moved off a holiday, so it covers another day.
+	occs[1].ShiftedFrom = date("2026-03-01")
+	occs[1].Period = run.tenant.periodFor(occurrence{Zone: occs[1].Zone, Schedule: dailySchedule, Due: occs[1].Due.AddDate(0, 0, -1)})
+	for _, occ := range occs {
+		run.trigger(t.Context(), occ)
+	}
+	run.flush(t.Context())
+	if len(srv.payloads) != 1 {
+		t.Fatalf("requests = %d, want one for the offset", len(srv.payloads))
+	}
+	p := srv.payloads[0]
+	if p["offset"] != "+05:30" || len(p["zones"].([]interface{})) != 3 {
+		t.Errorf("offset %v with zones %v, want +05:30 with all three", p["offset"], p["zones"])
+	}
+	if _, ok := p["period_start"]; ok {
+		t.Errorf("offset payload has a shared period_start %v", p["period_start"])
+	}
+	periods := p["zone_periods"].(map[string]interface{})
+	kolkata := periods["Asia/Kolkata"].(map[string]interface{})
+	colombo := periods["Asia/Colombo"].(map[string]interface{})
+	if kolkata["shifted"] != false || kolkata["period_start"] != "2026-03-02" {
+		t.Errorf("Asia/Kolkata details = %v", kolkata)
+	}
+	if colombo["shifted"] != true || colombo["shifted_from"] != "2026-03-01" || colombo["period_start"] != "2026-03-01" {
+		t.Errorf("Asia/Colombo details = %v", colombo)
+	}
+	if len(run.failed()) != 0 || len(run.results) != 3 {
+		t.Errorf("results = %v, want three delivered", run.results)
+	}
+}
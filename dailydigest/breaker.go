@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// errBreakerOpen is returned instead of attempting a delivery while the
+// breaker for its target is open.
+var errBreakerOpen = errors.New("circuit breaker open")
+
+type breakerState string
+
+const (
+	breakerClosed   breakerState = "closed"
+	breakerOpen     breakerState = "open"
+	breakerHalfOpen breakerState = "half_open"
+)
+
+// breaker trips after threshold consecutive failed attempts against one
+// target. Once cooldown has passed it lets a single probe through; the probe
+// either closes it again or restarts the cooldown.
+type breaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+
+	State    breakerState `json:"state"`
+	Failures int          `json:"failures"`
+	OpenedAt time.Time    `json:"opened_at"`
+	probing  bool
+}
+
+// breakers live at package level so their state survives warm invocations.
+var (
+	breakersMu sync.Mutex
+	breakers   = map[string]*breaker{}
+)
+
+// breakerFor returns the breaker for target, or nil when breaking is disabled.
+func breakerFor(c config, target string) *breaker {
+	if c.BreakerThreshold <= 0 {
+		return nil
+	}
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+	b, ok := breakers[target]
+	if !ok {
+		b = &breaker{State: breakerClosed}
+		breakers[target] = b
+	}
+	b.threshold, b.cooldown = c.BreakerThreshold, c.BreakerCooldown
+	return b
+}
+
+// allow reports whether an attempt may go out now.
+func (b *breaker) allow(now time.Time) bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.State {
+	case breakerOpen:
+		if now.Sub(b.OpenedAt) < b.cooldown {
+			return false
+		}
+		b.State = breakerHalfOpen
+		b.probing = true
+		slog.Info("circuit breaker half-open, probing", "outcome", "breaker_half_open")
+		return true
+	case breakerHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+// record feeds the outcome of one attempt back into the breaker.
+func (b *breaker) record(now time.Time, err error) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if err == nil {
+		if b.State != breakerClosed {
+			slog.Info("circuit breaker closed", "outcome", "breaker_closed")
+		}
+		b.State, b.Failures = breakerClosed, 0
+		return
+	}
+	b.Failures++
+	if b.State == breakerHalfOpen || b.Failures >= b.threshold {
+		if b.State != breakerOpen {
+			slog.Warn("circuit breaker open", "failures", b.Failures, "outcome", "breaker_open")
+		}
+		b.State, b.OpenedAt = breakerOpen, now
+	}
+}
+
+// abandon gives up an attempt without an outcome, freeing the half-open
+// probe for the next attempt.
+func (b *breaker) abandon() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	b.probing = false
+	b.mu.Unlock()
+}
+
+func breakerKey(target string) string {
+	return "breaker/" + url.PathEscape(target)
+}
+
+// loadBreaker refreshes the breaker for target from the shared store so a
+// cold instance does not hammer an endpoint another instance found down.
+func loadBreaker(ctx context.Context, store stateStore, b *breaker, target string) {
+	if store == nil || b == nil {
+		return
+	}
+	data, err := store.Get(ctx, breakerKey(target))
+	if err != nil {
+		if !errors.Is(err, errNotFound) {
+			slog.Warn("cannot load circuit breaker state", "target", target, "error", err)
+		}
+		return
+	}
+	var saved breaker
+	if err := json.Unmarshal(data, &saved); err != nil {
+		slog.Warn("cannot decode circuit breaker state", "target", target, "error", err)
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.State, b.Failures, b.OpenedAt = saved.State, saved.Failures, saved.OpenedAt
+	if b.State == breakerHalfOpen {
+		// the probe belonged to another instance; wait for it like an open breaker.
+		b.State = breakerOpen
+	}
+}
+
+// saveBreaker writes the breaker for target back to the shared store.
+func saveBreaker(ctx context.Context, store stateStore, b *breaker, target string) {
+	if store == nil || b == nil {
+		return
+	}
+	b.mu.Lock()
+	data, err := json.Marshal(b)
+	b.mu.Unlock()
+	if err != nil {
+		return
+	}
+	if err := store.Put(ctx, breakerKey(target), data); err != nil {
+		slog.Warn("cannot save circuit breaker state", "target", target, "error", err)
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var errDown = errors.New("connection refused")
+
+func newTestBreaker(threshold int, cooldown time.Duration) *breaker {
+	return &breaker{State: breakerClosed, threshold: threshold, cooldown: cooldown}
+}
+
+func TestBreakerOpensAfterThreshold(t *testing.T) {
+	now := time.Date(2026, time.March, 2, 15, 0, 0, 0, time.UTC)
+	b := newTestBreaker(3, time.Minute)
+	for i := 1; i <= 2; i++ {
+		b.record(now, errDown)
+		if b.State != breakerClosed || !b.allow(now) {
+			t.Fatalf("after %d failures state = %s, want closed", i, b.State)
+		}
+	}
+	b.record(now, nil)
+	if b.Failures != 0 {
+		t.Fatalf("success left %d failures", b.Failures)
+	}
+	for i := 0; i < 3; i++ {
+		b.record(now, errDown)
+	}
+	if b.State != breakerOpen || !b.OpenedAt.Equal(now) {
+		t.Fatalf("after threshold state = %s opened at %v, want open at %v", b.State, b.OpenedAt, now)
+	}
+	if b.allow(now.Add(30 * time.Second)) {
+		t.Error("open breaker allowed an attempt inside the cooldown")
+	}
+}
+
+func TestBreakerHalfOpenProbe(t *testing.T) {
+	now := time.Date(2026, time.March, 2, 15, 0, 0, 0, time.UTC)
+	b := newTestBreaker(1, time.Minute)
+	b.record(now, errDown)
+
+	later := now.Add(time.Minute)
+	if !b.allow(later) || b.State != breakerHalfOpen {
+		t.Fatalf("after the cooldown allow was refused or state = %s", b.State)
+	}
+	if b.allow(later) {
+		t.Error("a second attempt went out while the probe was in flight")
+	}
+	b.record(later, errDown)
+	if b.State != breakerOpen || !b.OpenedAt.Equal(later) {
+		t.Fatalf("failed probe left state %s opened at %v, want a restarted cooldown", b.State, b.OpenedAt)
+	}
+	if b.allow(later.Add(30 * time.Second)) {
+		t.Error("breaker allowed an attempt inside the restarted cooldown")
+	}
+
+	again := later.Add(time.Minute)
+	if !b.allow(again) {
+		t.Fatal("second probe refused")
+	}
+	b.abandon()
+	if !b.allow(again) {
+		t.Fatal("abandoned probe was not freed for the next attempt")
+	}
+	b.record(again, nil)
+	if b.State != breakerClosed || b.Failures != 0 || !b.allow(again) || !b.allow(again) {
+		t.Errorf("successful probe left state %s with %d failures, want closed", b.State, b.Failures)
+	}
+}
+
+func TestBreakerIgnoresClientErrorsAndCancellation(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusBadRequest)
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s := int(status.Load()); s != 0 {
+			w.WriteHeader(s)
+			return
+		}
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+	useConfig(t, func(c *config) { c.BreakerThreshold, c.BreakerCooldown = 1, time.Hour })
+	s := newSender(cfg)
+	tn := &tenant{ID: "default", Endpoint: srv.URL}
+	b := breakerFor(cfg, srv.URL)
+
+	for i := 0; i < 3; i++ {
+		if _, _, err := s.send(t.Context(), tn, []byte(`{}`)); err == nil {
+			t.Fatal("400 answer reported as delivered")
+		}
+	}
+	if b.State != breakerClosed || b.Failures != 0 {
+		t.Fatalf("4xx answers left state %s with %d failures", b.State, b.Failures)
+	}
+
+	status.Store(0)
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+	if _, _, err := s.send(ctx, tn, []byte(`{}`)); err == nil {
+		t.Fatal("cancelled attempt reported as delivered")
+	}
+	if b.State != breakerClosed || b.Failures != 0 {
+		t.Fatalf("cancelled attempt left state %s with %d failures", b.State, b.Failures)
+	}
+
+	status.Store(http.StatusServiceUnavailable)
+	s.send(t.Context(), tn, []byte(`{}`))
+	if b.State != breakerOpen {
+		t.Fatalf("503 answer left state %s, want open", b.State)
+	}
+	if _, attempts, err := s.send(t.Context(), tn, []byte(`{}`)); !errors.Is(err, errBreakerOpen) || attempts != 0 {
+		t.Errorf("send through an open breaker = %d attempts, %v", attempts, err)
+	}
+}
+
+func TestBreakerStateRoundTrip(t *testing.T) {
+	store := &fileStore{dir: t.TempDir()}
+	const target = "https://example.com/digest?tenant=a b"
+	opened := time.Date(2026, time.March, 2, 15, 0, 0, 0, time.UTC)
+
+	fresh := newTestBreaker(3, time.Minute)
+	loadBreaker(t.Context(), store, fresh, target)
+	if fresh.State != breakerClosed {
+		t.Fatalf("missing state loaded as %s", fresh.State)
+	}
+
+	saved := newTestBreaker(3, time.Minute)
+	saved.State, saved.Failures, saved.OpenedAt = breakerOpen, 4, opened
+	saveBreaker(t.Context(), store, saved, target)
+	got := newTestBreaker(3, time.Minute)
+	loadBreaker(t.Context(), store, got, target)
+	if got.State != breakerOpen || got.Failures != 4 || !got.OpenedAt.Equal(opened) {
+		t.Errorf("loaded %s/%d/%v, want open/4/%v", got.State, got.Failures, got.OpenedAt, opened)
+	}
+
+	// another instance's probe is waited out like an open breaker.
+	saved.State = breakerHalfOpen
+	saveBreaker(t.Context(), store, saved, target)
+	loadBreaker(t.Context(), store, got, target)
+	if got.State != breakerOpen {
+		t.Errorf("half-open state loaded as %s, want open", got.State)
+	}
+	if got.allow(opened.Add(30 * time.Second)) {
+		t.Error("loaded breaker allowed an attempt inside the cooldown")
+	}
+}
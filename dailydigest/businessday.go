@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Business-day modifiers a schedule can carry in business_days.
+const (
+	businessOnly     = "only"
+	businessNext     = "next"
+	businessPrevious = "previous"
+)
+
+// maxBusinessShift is how far a business day looks for occurrences moved
+// onto it.
+const maxBusinessShift = 14
+
+func checkBusinessDays(s schedule) error {
+	switch s.BusinessDays {
+	case "", businessOnly, businessNext, businessPrevious:
+		return nil
+	}
+	return fmt.Errorf("schedule for %s: business_days must be only, next or previous", s.CType)
+}
+
+// due decides on which days occ's schedule runs in its zone: its weekday,
+// work week or period anchor, then either its business_days modifier or,
+// without one, the holiday policy for its CType. A business day is a day
+// that is neither a weekend day of the zone's work week nor a holiday.
+func (tn *tenant) due(occ occurrence) dueFunc {
+	s := occ.Schedule
+	var cals []holidayCalendar
+	policy := holidaySend
+	if tn.holidays != nil {
+		cals = tn.holidays.calendarsFor(occ.Group, occ.Zone)
+		policy = tn.holidayPolicy(s.CType)
+	}
+	holiday := func(x time.Time) (string, bool) {
+		for _, cal := range cals {
+			if name, ok := cal.holiday(x); ok {
+				return name, true
+			}
+		}
+		return "", false
+	}
+	businessDay := func(x time.Time) bool {
+		_, hol := holiday(x)
+		return !tn.workWeekFor(occ.Group, occ.Zone, x).isWeekend(x.Weekday()) && !hol
+	}
+	onDay := func(x time.Time) bool {
+		switch {
+		case s.Period != "":
+			return sameDate(x, s.anchorDate(x, businessDay, tn.Fiscal))
+		case s.FirstWorkday:
+			return x.Weekday() == tn.workWeekFor(occ.Group, occ.Zone, x).FirstWorkday
+		}
+		return s.Weekday == nil || x.Weekday() == *s.Weekday
+	}
+	// moved looks from business day x across the non-business days before it
+	// (step -1) or after it (step 1) for an occurrence that should fire at x
+	// instead, and returns the farthest back or the nearest ahead. One moved
+	// onto a day the schedule already runs merges into that day's occurrence.
+	moved := func(x time.Time, step int, movable func(time.Time) bool) (bool, time.Time, *suppression) {
+		if !businessDay(x) {
+			return false, time.Time{}, nil
+		}
+		var from time.Time
+		for k := 1; k <= maxBusinessShift; k++ {
+			y := x.AddDate(0, 0, step*k)
+			if businessDay(y) {
+				break
+			}
+			if movable(y) {
+				from = y
+				if step > 0 {
+					break
+				}
+			}
+		}
+		return !from.IsZero(), from, nil
+	}
+	return func(x time.Time) (bool, time.Time, *suppression) {
+		if s.BusinessDays != "" {
+			if onDay(x) {
+				if businessDay(x) {
+					return true, time.Time{}, nil
+				}
+				name, _ := holiday(x)
+				return false, time.Time{}, &suppression{Occurrence: x, Reason: "non_business_day", Policy: s.BusinessDays, Holiday: name}
+			}
+			switch s.BusinessDays {
+			case businessNext:
+				return moved(x, -1, onDay)
+			case businessPrevious:
+				return moved(x, 1, onDay)
+			}
+			return false, time.Time{}, nil
+		}
+		if onDay(x) {
+			name, hol := holiday(x)
+			if !hol || policy == holidaySend {
+				return true, time.Time{}, nil
+			}
+			return false, time.Time{}, &suppression{Occurrence: x, Reason: "holiday", Policy: policy, Holiday: name}
+		}
+		if policy != holidayShift {
+			return false, time.Time{}, nil
+		}
+		return moved(x, -1, func(y time.Time) bool {
+			_, hol := holiday(y)
+			return hol && onDay(y)
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBusinessDayModifiers(t *testing.T) {
+	gb, err := loadHolidayCalendar(t.Context(), "builtin:GB")
+	if err != nil {
+		t.Fatal(err)
+	}
+	loc, err := loadLocation("Europe/London")
+	if err != nil {
+		t.Fatal(err)
+	}
+	monday := schedule{CType: TypeWeeklyAt9A, Hour: 9, Weekday: weekday(time.Monday)}
+	daily := schedule{CType: TypeDailyAt4P, Hour: 16}
+	with := func(s schedule, modifier string) schedule {
+		s.BusinessDays = modifier
+		return s
+	}
+	// Easter 2026 in England: Good Friday April 3, Easter Monday April 6.
+	tests := []struct {
+		name    string
+		sched   schedule
+		policy  string // holiday policy for the schedule's CType
+		date    string
+		due     bool
+		shifted string // date the occurrence moved from
+		reason  string // suppression reason
+		holiday string
+	}{
+		{"only on a business day", with(daily, businessOnly), "", "2026-04-02", true, "", "", ""},
+		{"only on a holiday", with(daily, businessOnly), "", "2026-04-03", false, "", "non_business_day", "Good Friday"},
+		{"only on a weekend", with(daily, businessOnly), "", "2026-04-04", false, "", "non_business_day", ""},
+		{"only off the weekday", with(monday, businessOnly), "", "2026-04-07", false, "", "", ""},
+		{"only on a holiday weekday", with(monday, businessOnly), "", "2026-04-06", false, "", "non_business_day", "Easter Monday"},
+		{"only on the next weekday", with(monday, businessOnly), "", "2026-04-13", true, "", "", ""},
+		{"next moves a holiday weekday", with(monday, businessNext), "", "2026-04-07", true, "2026-04-06", "", ""},
+		{"next stays off other days", with(monday, businessNext), "", "2026-04-08", false, "", "", ""},
+		{"next suppresses the holiday", with(monday, businessNext), "", "2026-04-06", false, "", "non_business_day", "Easter Monday"},
+		{"next merges into a running day", with(daily, businessNext), "", "2026-04-07", true, "", "", ""},
+		{"previous moves a holiday weekday", with(monday, businessPrevious), "", "2026-04-02", true, "2026-04-06", "", ""},
+		{"previous not onto a holiday", with(monday, businessPrevious), "", "2026-04-03", false, "", "", ""},
+		{"previous merges into a running day", with(daily, businessPrevious), "", "2026-04-02", true, "", "", ""},
+		{"modifier ignores the holiday policy", with(monday, businessOnly), holidaySend, "2026-04-06", false, "", "non_business_day", "Easter Monday"},
+		{"send on a holiday", monday, holidaySend, "2026-04-06", true, "", "", ""},
+		{"skip on a holiday", monday, holidaySkip, "2026-04-06", false, "", "holiday", "Easter Monday"},
+		{"skip does not move", monday, holidaySkip, "2026-04-07", false, "", "", ""},
+		{"shift suppresses the holiday", monday, holidayShift, "2026-04-06", false, "", "holiday", "Easter Monday"},
+		{"shift moves to the next business day", monday, holidayShift, "2026-04-07", true, "2026-04-06", "", ""},
+		{"shift leaves weekends alone", daily, holidayShift, "2026-04-04", true, "", "", ""},
+		{"shift merges into a running day", daily, holidayShift, "2026-04-07", true, "", "", ""},
+		{"default policy skips", monday, "", "2026-04-06", false, "", "holiday", "Easter Monday"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tn := &tenant{holidays: &holidays{zones: map[string][]holidayCalendar{"GB": {gb}}}}
+			if tt.policy != "" {
+				tn.HolidayPolicies = map[CType]string{tt.sched.CType: tt.policy}
+			}
+			d := date(tt.date)
+			x := time.Date(d.Year(), d.Month(), d.Day(), tt.sched.Hour, 0, 0, 0, loc)
+			due, from, sup := tn.due(occurrence{Group: "GMT", Zone: "Europe/London", Schedule: tt.sched})(x)
+			if due != tt.due {
+				t.Errorf("due = %v, want %v", due, tt.due)
+			}
+			if got := ""; !from.IsZero() {
+				if got = from.Format(time.DateOnly); got != tt.shifted {
+					t.Errorf("shifted from %s, want %q", got, tt.shifted)
+				}
+			} else if tt.shifted != "" {
+				t.Errorf("not shifted, want from %s", tt.shifted)
+			}
+			switch {
+			case sup == nil && tt.reason != "":
+				t.Errorf("not suppressed, want %s", tt.reason)
+			case sup != nil && (sup.Reason != tt.reason || sup.Holiday != tt.holiday || !sup.Occurrence.Equal(x)):
+				t.Errorf("suppression = %s %q at %s, want %s %q", sup.Reason, sup.Holiday, sup.Occurrence, tt.reason, tt.holiday)
+			}
+		})
+	}
+}
+
+func TestCheckBusinessDays(t *testing.T) {
+	for _, m := range []string{"", businessOnly, businessNext, businessPrevious} {
+		if err := checkBusinessDays(schedule{CType: TypeDailyAt4P, BusinessDays: m}); err != nil {
+			t.Errorf("%q: %v", m, err)
+		}
+	}
+	if err := checkBusinessDays(schedule{CType: TypeDailyAt4P, BusinessDays: "weekdays"}); err == nil {
+		t.Error("unknown modifier accepted")
+	}
+}
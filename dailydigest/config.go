@@ -0,0 +1,203 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// config holds the knobs read from the Lambda environment. Every field has a
+// default that keeps the original one-request-per-zone behaviour.
+type config struct {
+	// LogLevel is the minimum slog level: debug, info, warn or error.
+	LogLevel string
+
+	// Tenants is a secret reference to a JSON array of tenants. Empty
+	// serves a single tenant built from Endpoint and the OAuth settings.
+	Tenants string
+
+	// Subscriptions names the source of active subscribers (see
+	// loadSubscriptions); empty evaluates every catalog zone. The fetched
+	// set is reused for SubscriptionsTTL.
+	Subscriptions    string
+	SubscriptionsTTL time.Duration
+
+	// QuietHours is a secret reference to a JSON array of quiet windows
+	// that apply to every tenant.
+	QuietHours string
+	// CTypes switches digest types on for the default tenant; empty means
+	// the daily and weekly digests.
+	CTypes []CType
+	// Holidays is a secret reference to the holiday calendar settings.
+	Holidays string
+	// WorkWeeks is a secret reference to work week overrides.
+	WorkWeeks string
+	// Messages is a secret reference to message templates layered over the
+	// built-in catalog.
+	Messages string
+
+	Endpoint string
+	Timeout  time.Duration
+	// HealthPath is requested with GET by the healthcheck action, resolved
+	// against Endpoint. Empty sends a HEAD to Endpoint itself.
+	HealthPath string
+
+	// Metrics turns on CloudWatch Embedded Metric Format output under
+	// MetricsNamespace.
+	Metrics          bool
+	MetricsNamespace string
+
+	// MaxAttempts is how many times a delivery is tried before it is given
+	// up on. RetryBackoff is the wait before the second attempt and doubles
+	// after that.
+	MaxAttempts  int
+	RetryBackoff time.Duration
+
+	// BatchMode groups every zone that fired for a CType into one request.
+	BatchMode bool
+	// BatchSize caps the zones per batch request. Zero means no cap. Offset
+	// fan-out groups are never split.
+	BatchSize int
+	// FanOut is "zone", one trigger per zone or batch, or "offset", one
+	// trigger per UTC offset with the zones on it.
+	FanOut string
+
+	// DeadLetterKind is one of "file", "s3" or "sqs"; empty disables
+	// dead-letter capture. DeadLetterTarget is the file path, the
+	// "bucket/prefix" pair or the queue URL respectively.
+	DeadLetterKind   string
+	DeadLetterTarget string
+	// ReportKind is "file", "s3" or "state"; empty disables run reports.
+	// ReportTarget is the JSONL file path or the "bucket/prefix" pair.
+	ReportKind   string
+	ReportTarget string
+
+	// StateKind is "file" or "s3"; empty keeps state in memory only.
+	// StateTarget is the directory or the "bucket/prefix" pair.
+	StateKind   string
+	StateTarget string
+
+	// BreakerThreshold is the number of consecutive failed attempts that
+	// opens the circuit breaker; zero disables it. BreakerCooldown is how
+	// long it stays open before a half-open probe.
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+
+	// TLSCert, TLSKey and TLSCA are secret references (see loadSecret) for
+	// the client certificate, its key and a private CA bundle. They are
+	// re-read every TLSReload.
+	TLSCert       string
+	TLSKey        string
+	TLSCA         string
+	TLSMinVersion string
+	TLSServerName string
+	TLSReload     time.Duration
+
+	// OAuthTokenURL enables OAuth2 client-credentials authentication.
+	// OAuthClientSecret is a secret reference. Tokens are refreshed
+	// OAuthRefreshBefore their expiry.
+	OAuthTokenURL      string
+	OAuthClientID      string
+	OAuthClientSecret  string
+	OAuthScope         string
+	OAuthAudience      string
+	OAuthRefreshBefore time.Duration
+
+	// OTLPEndpoint is the OTLP/HTTP collector URL spans are exported to;
+	// empty keeps tracing a no-op. ServiceName is the service.name resource.
+	OTLPEndpoint string
+	ServiceName  string
+
+	// S3Endpoint overrides the S3 endpoint for S3-compatible stores.
+	S3Endpoint string
+}
+
+func loadConfig() config {
+	return config{
+		LogLevel:           envString("DIGEST_LOG_LEVEL", "info"),
+		Tenants:            envString("DIGEST_TENANTS", ""),
+		Subscriptions:      envString("DIGEST_SUBSCRIPTIONS", ""),
+		SubscriptionsTTL:   envDuration("DIGEST_SUBSCRIPTIONS_TTL", 5*time.Minute),
+		QuietHours:         envString("DIGEST_QUIET_HOURS", ""),
+		CTypes:             envCTypes("DIGEST_CTYPES"),
+		Holidays:           envString("DIGEST_HOLIDAYS", ""),
+		WorkWeeks:          envString("DIGEST_WORK_WEEKS", ""),
+		Messages:           envString("DIGEST_MESSAGES", ""),
+		Endpoint:           envString("DIGEST_ENDPOINT", "<YOUR APP ENDPOINT>"),
+		Timeout:            envDuration("DIGEST_TIMEOUT", 10*time.Second),
+		HealthPath:         envString("DIGEST_HEALTH_PATH", ""),
+		Metrics:            envBool("DIGEST_METRICS", false),
+		MetricsNamespace:   envString("DIGEST_METRICS_NAMESPACE", "DailyDigest"),
+		MaxAttempts:        envInt("DIGEST_MAX_ATTEMPTS", 1),
+		RetryBackoff:       envDuration("DIGEST_RETRY_BACKOFF", 500*time.Millisecond),
+		BatchMode:          envBool("DIGEST_BATCH_MODE", false),
+		BatchSize:          envInt("DIGEST_BATCH_SIZE", 0),
+		FanOut:             envString("DIGEST_FANOUT", fanOutZone),
+		DeadLetterKind:     envString("DIGEST_DEADLETTER_KIND", ""),
+		DeadLetterTarget:   envString("DIGEST_DEADLETTER_TARGET", ""),
+		ReportKind:         envString("DIGEST_REPORT_KIND", ""),
+		ReportTarget:       envString("DIGEST_REPORT_TARGET", ""),
+		StateKind:          envString("DIGEST_STATE_KIND", ""),
+		StateTarget:        envString("DIGEST_STATE_TARGET", ""),
+		BreakerThreshold:   envInt("DIGEST_BREAKER_THRESHOLD", 0),
+		BreakerCooldown:    envDuration("DIGEST_BREAKER_COOLDOWN", time.Minute),
+		TLSCert:            envString("DIGEST_TLS_CERT", ""),
+		TLSKey:             envString("DIGEST_TLS_KEY", ""),
+		TLSCA:              envString("DIGEST_TLS_CA", ""),
+		TLSMinVersion:      envString("DIGEST_TLS_MIN_VERSION", ""),
+		TLSServerName:      envString("DIGEST_TLS_SERVER_NAME", ""),
+		TLSReload:          envDuration("DIGEST_TLS_RELOAD", 5*time.Minute),
+		OAuthTokenURL:      envString("DIGEST_OAUTH_TOKEN_URL", ""),
+		OAuthClientID:      envString("DIGEST_OAUTH_CLIENT_ID", ""),
+		OAuthClientSecret:  envString("DIGEST_OAUTH_CLIENT_SECRET", ""),
+		OAuthScope:         envString("DIGEST_OAUTH_SCOPE", ""),
+		OAuthAudience:      envString("DIGEST_OAUTH_AUDIENCE", ""),
+		OAuthRefreshBefore: envDuration("DIGEST_OAUTH_REFRESH_BEFORE", time.Minute),
+		OTLPEndpoint:       envString("DIGEST_OTLP_ENDPOINT", ""),
+		ServiceName:        envString("DIGEST_SERVICE_NAME", envString("AWS_LAMBDA_FUNCTION_NAME", "dailydigest")),
+		S3Endpoint:         envString("DIGEST_S3_ENDPOINT", ""),
+	}
+}
+
+func envString(key, def string) string {
+	if v, ok := os.LookupEnv(key); ok && strings.TrimSpace(v) != "" {
+		return strings.TrimSpace(v)
+	}
+	return def
+}
+
+func envBool(key string, def bool) bool {
+	v, err := strconv.ParseBool(envString(key, strconv.FormatBool(def)))
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+func envInt(key string, def int) int {
+	v, err := strconv.Atoi(envString(key, strconv.Itoa(def)))
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+func envDuration(key string, def time.Duration) time.Duration {
+	v, err := time.ParseDuration(envString(key, def.String()))
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+// envCTypes reads a comma-separated list of digest types.
+func envCTypes(key string) []CType {
+	var out []CType
+	for _, v := range strings.Split(envString(key, ""), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, CType(v))
+		}
+	}
+	return out
+}
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+)
+
+// deadLetter is a delivery that failed after every retry. It carries the
+// exact payload, minus the body token, so a redrive sends what the original
+// run would have sent with the tenant's current token.
+type deadLetter struct {
+	Tenant    string          `json:"tenant,omitempty"`
+	Target    string          `json:"target"`
+	CType     CType           `json:"type"`
+	Zones     []string        `json:"zones"`
+	Payload   json.RawMessage `json:"payload"`
+	Attempts  int             `json:"attempts"`
+	LastError string          `json:"last_error"`
+	FailedAt  time.Time       `json:"failed_at"`
+}
+
+// deadLetterSink stores failed deliveries. Drain hands every stored entry to
+// fn: a nil result removes the entry, anything else replaces it.
+type deadLetterSink interface {
+	Put(ctx context.Context, dl deadLetter) error
+	Drain(ctx context.Context, fn func(deadLetter) *deadLetter) error
+}
+
+// newDeadLetterSink builds the sink named by DIGEST_DEADLETTER_KIND. It returns
+// nil when dead-letter capture is not configured.
+func newDeadLetterSink(ctx context.Context, c config) (deadLetterSink, error) {
+	switch c.DeadLetterKind {
+	case "":
+		return nil, nil
+	case "file":
+		return &fileDeadLetters{path: c.DeadLetterTarget}, nil
+	case "s3":
+		bucket, prefix, _ := strings.Cut(c.DeadLetterTarget, "/")
+		client, err := newS3Client(ctx, c.S3Endpoint)
+		if err != nil {
+			return nil, err
+		}
+		return &s3DeadLetters{client: client, bucket: bucket, prefix: prefix}, nil
+	case "sqs":
+		awsCfg, err := awsconfig.LoadDefaultConfig(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &sqsDeadLetters{client: sqs.NewFromConfig(awsCfg), queueURL: c.DeadLetterTarget}, nil
+	default:
+		return nil, fmt.Errorf("unknown dead-letter kind %q", c.DeadLetterKind)
+	}
+}
+
+// newS3Client returns an S3 client. A non-empty endpoint points it at an
+// S3-compatible store such as MinIO, which needs path-style addressing.
+func newS3Client(ctx context.Context, endpoint string) (*s3.Client, error) {
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+		if endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+			o.UsePathStyle = true
+		}
+	}), nil
+}
+
+// fileDeadLetters appends entries to a local JSONL file.
+type fileDeadLetters struct {
+	path string
+}
+
+func (f *fileDeadLetters) Put(ctx context.Context, dl deadLetter) error {
+	line, err := json.Marshal(dl)
+	if err != nil {
+		return err
+	}
+	return appendLine(f.path, line)
+}
+
+func (f *fileDeadLetters) Drain(ctx context.Context, fn func(deadLetter) *deadLetter) error {
+	data, err := os.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var keep bytes.Buffer
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var dl deadLetter
+		if err := json.Unmarshal(line, &dl); err != nil {
+			// never drop a line we cannot read.
+			keep.Write(line)
+			keep.WriteByte('\n')
+			continue
+		}
+		if updated := fn(dl); updated != nil {
+			if line, err = json.Marshal(updated); err != nil {
+				return err
+			}
+			keep.Write(line)
+			keep.WriteByte('\n')
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return os.WriteFile(f.path, keep.Bytes(), 0o644)
+}
+
+// s3DeadLetters stores one object per entry under prefix.
+type s3DeadLetters struct {
+	client *s3.Client
+	bucket string
+	prefix string
+}
+
+func (b *s3DeadLetters) Put(ctx context.Context, dl deadLetter) error {
+	key := path.Join(b.prefix, fmt.Sprintf("%s-%s.json", dl.FailedAt.Format("20060102T150405.000000000Z"), dl.CType))
+	return b.put(ctx, key, dl)
+}
+
+func (b *s3DeadLetters) put(ctx context.Context, key string, dl deadLetter) error {
+	body, err := json.Marshal(dl)
+	if err != nil {
+		return err
+	}
+	_, err = b.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(b.bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(body),
+		ContentType: aws.String("application/json"),
+	})
+	return err
+}
+
+func (b *s3DeadLetters) Drain(ctx context.Context, fn func(deadLetter) *deadLetter) error {
+	pages := s3.NewListObjectsV2Paginator(b.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(b.bucket),
+		Prefix: aws.String(b.prefix),
+	})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		for _, obj := range page.Contents {
+			out, err := b.client.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String(b.bucket), Key: obj.Key})
+			if err != nil {
+				return err
+			}
+			var dl deadLetter
+			err = json.NewDecoder(out.Body).Decode(&dl)
+			out.Body.Close()
+			if err != nil {
+				continue
+			}
+			if updated := fn(dl); updated != nil {
+				if err := b.put(ctx, aws.ToString(obj.Key), *updated); err != nil {
+					return err
+				}
+				continue
+			}
+			if _, err := b.client.DeleteObject(ctx, &s3.DeleteObjectInput{Bucket: aws.String(b.bucket), Key: obj.Key}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// sqsDeadLetters sends one message per entry. Messages cannot be edited, so
+// an entry that fails to redrive is re-sent as a new message before the
+// original is deleted. Drain long-polls until a receive comes back empty or
+// sqsDrainLimit messages were handled.
+const (
+	sqsDrainWait  = 5
+	sqsDrainLimit = 1000
+)
+
+type sqsDeadLetters struct {
+	client   *sqs.Client
+	queueURL string
+}
+
+func (q *sqsDeadLetters) Put(ctx context.Context, dl deadLetter) error {
+	body, err := json.Marshal(dl)
+	if err != nil {
+		return err
+	}
+	_, err = q.client.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:    aws.String(q.queueURL),
+		MessageBody: aws.String(string(body)),
+	})
+	return err
+}
+
+func (q *sqsDeadLetters) Drain(ctx context.Context, fn func(deadLetter) *deadLetter) error {
+	seen := map[string]bool{}
+	for handled := 0; handled < sqsDrainLimit; {
+		out, err := q.client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+			QueueUrl:            aws.String(q.queueURL),
+			MaxNumberOfMessages: 10,
+			WaitTimeSeconds:     sqsDrainWait,
+		})
+		if err != nil {
+			return err
+		}
+		if len(out.Messages) == 0 {
+			return nil
+		}
+		for _, msg := range out.Messages {
+			if seen[aws.ToString(msg.MessageId)] {
+				// requeued during this drain; it stays for the next redrive.
+				continue
+			}
+			handled++
+			var dl deadLetter
+			if err := json.Unmarshal([]byte(aws.ToString(msg.Body)), &dl); err != nil {
+				continue
+			}
+			if updated := fn(dl); updated != nil {
+				sent, err := q.client.SendMessage(ctx, &sqs.SendMessageInput{
+					QueueUrl:    aws.String(q.queueURL),
+					MessageBody: aws.String(string(mustJSON(updated))),
+				})
+				if err != nil {
+					return err
+				}
+				seen[aws.ToString(sent.MessageId)] = true
+			}
+			if _, err := q.client.DeleteMessage(ctx, &sqs.DeleteMessageInput{
+				QueueUrl:      aws.String(q.queueURL),
+				ReceiptHandle: msg.ReceiptHandle,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	slog.Warn("dead-letter queue drain stopped at its limit, run redrive again for the rest", "limit", sqsDrainLimit)
+	return nil
+}
+
+// runRedrive replays every dead-lettered delivery through the normal sender
+// and its circuit breakers, shared through the state store like a digest
+// run's. Entries that still fail stay in the sink with their attempt count
+// bumped.
+func runRedrive(ctx context.Context) error {
+	sink, err := newDeadLetterSink(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	if sink == nil {
+		return fmt.Errorf("redrive requested but no dead-letter sink is configured")
+	}
+	tenants, err := loadTenants(ctx, cfg)
+	if err != nil {
+		slog.Error("some tenants could not be loaded, their entries stay dead-lettered", "error", err)
+	}
+	store, serr := newStateStore(ctx, cfg)
+	if serr != nil {
+		slog.Error("state store unavailable, circuit breaker state stays local", "error", serr)
+	}
+	loaded := map[string]bool{}
+	defer func() {
+		for target := range loaded {
+			saveBreaker(ctx, store, breakerFor(cfg, target), target)
+		}
+	}()
+	s := newSender(cfg)
+	var replayed, failed int
+	err = sink.Drain(ctx, func(dl deadLetter) *deadLetter {
+		id := dl.Tenant
+		if id == "" {
+			id = defaultTenantID
+		}
+		tn := tenantByID(tenants, id)
+		if tn == nil {
+			failed++
+			slog.Warn("redrive skipped, unknown tenant", "tenant", id, "ctype", dl.CType, "zones", dl.Zones, "outcome", "failed")
+			return &dl
+		}
+		if dl.Target != "" && dl.Target != tn.Endpoint {
+			// the tenant moved since; deliver where it receives digests now.
+			slog.Info("redrive target changed", "tenant", tn.ID, "from", dl.Target, "to", tn.Endpoint)
+		}
+		if !loaded[tn.Endpoint] {
+			loaded[tn.Endpoint] = true
+			loadBreaker(ctx, store, breakerFor(cfg, tn.Endpoint), tn.Endpoint)
+		}
+		_, attempts, err := s.send(ctx, tn, dl.Payload)
+		if err == nil {
+			replayed++
+			slog.Info("redrive delivered", "tenant", tn.ID, "ctype", dl.CType, "zones", dl.Zones, "outcome", "delivered")
+			return nil
+		}
+		failed++
+		slog.Warn("redrive still failing", "tenant", tn.ID, "ctype", dl.CType, "zones", dl.Zones, "error", err, "outcome", "failed")
+		dl.Attempts += attempts
+		dl.LastError = err.Error()
+		dl.Target = tn.Endpoint
+		dl.FailedAt = time.Now().UTC()
+		return &dl
+	})
+	slog.Info("redrive finished", "delivered", replayed, "failed", failed)
+	if err == nil && failed > 0 {
+		err = fmt.Errorf("%d dead-lettered digests still failing", failed)
+	}
+	return err
+}
+
+func mustJSON(v interface{}) []byte {
+	b, _ := json.Marshal(v)
+	return b
+}
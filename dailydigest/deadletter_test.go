@@ -0,0 +1,122 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readDeadLetters(t *testing.T, sink deadLetterSink) []deadLetter {
+	t.Helper()
+	var out []deadLetter
+	if err := sink.Drain(t.Context(), func(dl deadLetter) *deadLetter {
+		out = append(out, dl)
+		return &dl
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestFileDeadLettersPutAndDrain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dead.jsonl")
+	sink := &fileDeadLetters{path: path}
+	if got := readDeadLetters(t, sink); len(got) != 0 {
+		t.Fatalf("missing file drained %d entries", len(got))
+	}
+	failedAt := time.Date(2026, time.March, 2, 15, 5, 0, 0, time.UTC)
+	for _, z := range []string{"Europe/Paris", "Europe/Berlin", "Europe/Rome"} {
+		dl := deadLetter{Tenant: "acme", Target: "https://acme.example.com/digest", CType: TypeDailyAt4P,
+			Zones: []string{z}, Payload: []byte(`{"zone":"` + z + `"}`), Attempts: 3, LastError: "503", FailedAt: failedAt}
+		if err := sink.Put(t.Context(), dl); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("not json\n\n")
+	f.Close()
+
+	err = sink.Drain(t.Context(), func(dl deadLetter) *deadLetter {
+		switch dl.Zones[0] {
+		case "Europe/Paris":
+			return nil
+		case "Europe/Berlin":
+			dl.Attempts++
+			dl.LastError = "still down"
+		}
+		return &dl
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := readDeadLetters(t, sink)
+	if len(got) != 2 {
+		t.Fatalf("after drain %d entries, want Berlin and Rome", len(got))
+	}
+	if got[0].Zones[0] != "Europe/Berlin" || got[0].Attempts != 4 || got[0].LastError != "still down" {
+		t.Errorf("updated entry = %+v", got[0])
+	}
+	if got[1].Zones[0] != "Europe/Rome" || got[1].Attempts != 3 || !got[1].FailedAt.Equal(failedAt) || string(got[1].Payload) != `{"zone":"Europe/Rome"}` {
+		t.Errorf("kept entry = %+v", got[1])
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), "not json\n") {
+		t.Error("unreadable line was dropped")
+	}
+}
+
+func TestRedriveRequeuesFailures(t *testing.T) {
+	srv := newDigestServer(t, failZones(http.StatusServiceUnavailable, "Europe/Berlin"))
+	path := filepath.Join(t.TempDir(), "dead.jsonl")
+	useConfig(t, func(c *config) {
+		c.Endpoint = srv.URL
+		c.DeadLetterKind, c.DeadLetterTarget = "file", path
+	})
+	sink := &fileDeadLetters{path: path}
+	for _, dl := range []deadLetter{
+		{Target: "https://old.example.com/digest", CType: TypeDailyAt4P, Zones: []string{"Europe/Paris"}, Payload: []byte(`{"zone":"Europe/Paris"}`), Attempts: 3},
+		{Tenant: defaultTenantID, CType: TypeDailyAt4P, Zones: []string{"Europe/Berlin"}, Payload: []byte(`{"zone":"Europe/Berlin"}`), Attempts: 3},
+		{Tenant: "gone", CType: TypeDailyAt4P, Zones: []string{"Europe/Rome"}, Payload: []byte(`{"zone":"Europe/Rome"}`), Attempts: 3},
+	} {
+		if err := sink.Put(t.Context(), dl); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := runRedrive(t.Context())
+	if err == nil || !strings.Contains(err.Error(), "2 dead-lettered digests still failing") {
+		t.Errorf("runRedrive error = %v, want two still failing", err)
+	}
+	if len(srv.payloads) != 2 {
+		t.Errorf("redrive posted %d payloads, want Paris and Berlin", len(srv.payloads))
+	}
+	got := readDeadLetters(t, sink)
+	if len(got) != 2 {
+		t.Fatalf("after redrive %d entries, want Berlin and Rome", len(got))
+	}
+	berlin, rome := got[0], got[1]
+	if berlin.Zones[0] != "Europe/Berlin" || berlin.Attempts != 4 || berlin.Target != srv.URL || berlin.LastError == "" {
+		t.Errorf("requeued entry = %+v", berlin)
+	}
+	if rome.Tenant != "gone" || rome.Attempts != 3 {
+		t.Errorf("unknown tenant entry = %+v, want it kept unchanged", rome)
+	}
+
+	srv2 := newDigestServer(t, nil)
+	useConfig(t, func(c *config) {
+		c.Endpoint = srv2.URL
+		c.DeadLetterKind, c.DeadLetterTarget = "file", path
+	})
+	if err := runRedrive(t.Context()); err == nil {
+		t.Error("unknown tenant's entry counted as delivered")
+	}
+	if got := readDeadLetters(t, sink); len(got) != 1 || got[0].Tenant != "gone" {
+		t.Errorf("after the second redrive entries = %+v, want only the unknown tenant's", got)
+	}
+}
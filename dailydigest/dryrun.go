@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// maxSimulationSteps bounds how many evaluations one simulation may run.
+const maxSimulationSteps = 10000
+
+// plannedDigest is an occurrence as a dry run or simulation sees it: either a
+// trigger that would be sent, or, with DueAt and Reason set, one that quiet
+// hours or a holiday suppressed.
+type plannedDigest struct {
+	Tenant       string    `json:"tenant"`
+	EvaluatedAt  time.Time `json:"evaluated_at"`
+	Zone         string    `json:"zone"`
+	Group        string    `json:"group"`
+	CType        CType     `json:"type"`
+	LocalTime    string    `json:"local_time"`
+	Offset       string    `json:"offset,omitempty"`
+	DeferredFrom string    `json:"deferred_from,omitempty"`
+	ShiftedFrom  string    `json:"shifted_from,omitempty"`
+	DueAt        string    `json:"due_at,omitempty"`
+	Reason       string    `json:"reason,omitempty"`
+	Policy       string    `json:"policy,omitempty"`
+	Window       string    `json:"window,omitempty"`
+	Holiday      string    `json:"holiday,omitempty"`
+}
+
+// dryRunPlan is the result of the "dryrun" and "simulate" actions.
+type dryRunPlan struct {
+	From       time.Time       `json:"from"`
+	To         time.Time       `json:"to"`
+	Step       string          `json:"step,omitempty"`
+	Triggers   []plannedDigest `json:"triggers"`
+	Suppressed []plannedDigest `json:"suppressed"`
+}
+
+func runSimulation(ctx context.Context, ev cronEvent) (*dryRunPlan, error) {
+	step := triggerFrequency * time.Minute
+	if ev.Step != "" {
+		d, err := time.ParseDuration(ev.Step)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid simulation step %q", ev.Step)
+		}
+		step = d
+	}
+	if ev.From.IsZero() || ev.To.Before(ev.From) {
+		return nil, fmt.Errorf("simulation needs from <= to")
+	}
+	if ev.To.Sub(ev.From)/step >= maxSimulationSteps {
+		return nil, fmt.Errorf("simulation of %s every %s exceeds %d steps", ev.To.Sub(ev.From), step, maxSimulationSteps)
+	}
+	return runDryRun(ctx, ev.From, ev.To, step)
+}
+
+// runDryRun evaluates every tenant at each step from from to to as the
+// scheduled run would, without sending, dead-lettering or touching breaker
+// state.
+func runDryRun(ctx context.Context, from, to time.Time, step time.Duration) (*dryRunPlan, error) {
+	tenants, err := loadTenants(ctx, cfg)
+	if err != nil {
+		slog.Error("some tenants could not be loaded", "error", err, "outcome", "tenant_error")
+	}
+	store, serr := newStateStore(ctx, cfg)
+	if serr != nil {
+		slog.Warn("state store unavailable", "error", serr)
+	}
+	plan := &dryRunPlan{From: from.UTC(), To: to.UTC(), Triggers: []plannedDigest{}, Suppressed: []plannedDigest{}}
+	if step > 0 {
+		plan.Step = step.String()
+	}
+	base := &digestRun{dryRun: true, plan: plan, byOffset: cfg.FanOut == fanOutOffset, subs: loadSubscriptions(ctx, cfg, store)}
+	for t := from.UTC(); !t.After(to); t = t.Add(step) {
+		base.at = t
+		for _, tn := range tenants {
+			if terr := evaluateTenant(ctx, t, base.forTenant(ctx, tn)); terr != nil {
+				err = errors.Join(err, terr)
+			}
+		}
+		if step <= 0 {
+			break
+		}
+	}
+	slog.Info("dry run finished", "from", plan.From, "to", plan.To, "triggers", len(plan.Triggers), "suppressed", len(plan.Suppressed))
+	return plan, err
+}
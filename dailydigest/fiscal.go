@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	periodFiscalPeriod = "fiscal_period"
+	periodFiscalWeek   = "fiscal_week"
+)
+
+// fiscalPatterns are the supported week-based period patterns, one quarter's
+// worth of weeks per entry.
+var fiscalPatterns = map[string][]int{
+	"4-4-5": {4, 4, 5},
+	"4-5-4": {4, 5, 4},
+	"5-4-4": {5, 4, 4},
+}
+
+// fiscalCalendar is a tenant's fiscal year. With the "calendar" pattern (the
+// default) its twelve periods are months counted from YearStart. With a
+// week-based pattern such as "4-4-5" the year starts on the WeekStart day
+// nearest YearStart, so it has 52 or 53 weeks, and the last period takes the
+// 53rd week. Label names a fiscal year after the calendar year it ends in
+// ("end", the default) or starts in ("start").
+type fiscalCalendar struct {
+	YearStart string `json:"year_start"`
+	Pattern   string `json:"pattern,omitempty"`
+	WeekStart string `json:"week_start,omitempty"`
+	Label     string `json:"label,omitempty"`
+
+	month     time.Month
+	day       int
+	weeks     []int
+	weekStart time.Weekday
+}
+
+// fiscalID identifies where a date falls in the fiscal calendar.
+type fiscalID struct {
+	Year    string
+	Quarter int
+	Period  int
+	Week    int
+}
+
+func (f *fiscalCalendar) prepare() error {
+	t, err := time.Parse("01-02", f.YearStart)
+	if err != nil {
+		return fmt.Errorf("fiscal year_start %q, want MM-DD", f.YearStart)
+	}
+	f.month, f.day = t.Month(), t.Day()
+	f.weekStart = time.Monday
+	if f.WeekStart != "" {
+		if f.weekStart, err = parseWeekday(f.WeekStart); err != nil {
+			return fmt.Errorf("fiscal week_start: %w", err)
+		}
+	}
+	switch f.Pattern {
+	case "", "calendar":
+		if f.day > 28 {
+			return fmt.Errorf("fiscal year_start %s: calendar periods need a day up to 28", f.YearStart)
+		}
+	default:
+		q, ok := fiscalPatterns[f.Pattern]
+		if !ok {
+			return fmt.Errorf("unknown fiscal pattern %q", f.Pattern)
+		}
+		f.weeks = append(append(append(append([]int(nil), q...), q...), q...), q...)
+	}
+	switch f.Label {
+	case "", "end", "start":
+	default:
+		return fmt.Errorf("fiscal label must be end or start")
+	}
+	return nil
+}
+
+// yearStart returns the first day of the fiscal year that nominally begins
+// in calendar year y.
+func (f *fiscalCalendar) yearStart(y int, loc *time.Location) time.Time {
+	d := localMidnight(y, f.month, f.day, loc)
+	if f.weeks == nil {
+		return d
+	}
+	diff := (int(f.weekStart) - int(d.Weekday()) + 7) % 7
+	if diff > 3 {
+		diff -= 7
+	}
+	return addDays(d, diff)
+}
+
+// year returns the start of the fiscal year containing day, the start of the
+// next one, and the year's label.
+func (f *fiscalCalendar) year(day time.Time) (start, next time.Time, label string) {
+	loc := day.Location()
+	y := day.Year()
+	if start = f.yearStart(y+1, loc); !day.Before(start) {
+		y++
+	} else if start = f.yearStart(y, loc); day.Before(start) {
+		y--
+		start = f.yearStart(y, loc)
+	}
+	next = f.yearStart(y+1, loc)
+	named := y
+	if f.Label != "start" {
+		// the calendar year of the nominal last day.
+		named = time.Date(y+1, f.month, f.day-1, 0, 0, 0, 0, time.UTC).Year()
+	}
+	return start, next, fmt.Sprintf("FY%d", named)
+}
+
+// period returns the 1-based fiscal period containing day with its first day
+// and the first day of the next period.
+func (f *fiscalCalendar) period(day time.Time) (n int, start, next time.Time) {
+	yStart, yNext, _ := f.year(day)
+	loc := day.Location()
+	start = yStart
+	for n = 1; n <= 12; n++ {
+		if n == 12 {
+			return n, start, yNext
+		}
+		if f.weeks == nil {
+			y, m, d := yStart.Date()
+			next = localMidnight(y, m+time.Month(n), d, loc)
+		} else {
+			next = addDays(start, 7*f.weeks[n-1])
+		}
+		if day.Before(next) {
+			return n, start, next
+		}
+		start = next
+	}
+	return 12, start, yNext
+}
+
+// week returns the first day of the fiscal week containing day.
+func (f *fiscalCalendar) week(day time.Time) time.Time {
+	return addDays(day, -((int(day.Weekday()) - int(f.weekStart) + 7) % 7))
+}
+
+// id places day in the fiscal calendar. Week 1 is the week the fiscal year
+// starts in.
+func (f *fiscalCalendar) id(day time.Time) fiscalID {
+	yStart, _, label := f.year(day)
+	n, _, _ := f.period(day)
+	return fiscalID{
+		Year:    label,
+		Quarter: (n-1)/3 + 1,
+		Period:  n,
+		Week:    daysBetween(f.week(yStart), f.week(day))/7 + 1,
+	}
+}
+
+// daysBetween counts calendar days from a to b, ignoring DST.
+func daysBetween(a, b time.Time) int {
+	ad := time.Date(a.Year(), a.Month(), a.Day(), 0, 0, 0, 0, time.UTC)
+	bd := time.Date(b.Year(), b.Month(), b.Day(), 0, 0, 0, 0, time.UTC)
+	return int(bd.Sub(ad).Hours() / 24)
+}
+
+// bounds returns the first and last day of the fiscal period or week
+// containing day.
+func (f *fiscalCalendar) bounds(period string, day time.Time) (start, end time.Time) {
+	var next time.Time
+	if period == periodFiscalWeek {
+		start = f.week(day)
+		next = addDays(start, 7)
+	} else {
+		_, start, next = f.period(day)
+	}
+	return start, addDays(next, -1)
+}
+
+// payload adds the fiscal identifiers to a trigger payload.
+func (id fiscalID) payload(into map[string]interface{}) {
+	if id.Year == "" {
+		return
+	}
+	into["fiscal_year"] = id.Year
+	into["fiscal_quarter"] = id.Quarter
+	into["fiscal_period"] = id.Period
+	into["fiscal_week"] = id.Week
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFiscal445(t *testing.T) {
+	loc, err := loadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fc := &fiscalCalendar{YearStart: "01-01", Pattern: "4-4-5"}
+	if err := fc.prepare(); err != nil {
+		t.Fatal(err)
+	}
+	// FY2026 starts on Monday 2025-12-29, the Monday nearest January 1, and
+	// runs 53 weeks to 2027-01-03; FY2025 ran 52 weeks from 2024-12-30.
+	tests := []struct {
+		date       string
+		id         fiscalID
+		start, end string // fiscal period
+	}{
+		{"2025-12-28", fiscalID{"FY2025", 4, 12, 52}, "2025-11-24", "2025-12-28"},
+		{"2025-12-29", fiscalID{"FY2026", 1, 1, 1}, "2025-12-29", "2026-01-25"},
+		{"2026-01-25", fiscalID{"FY2026", 1, 1, 4}, "2025-12-29", "2026-01-25"},
+		{"2026-01-26", fiscalID{"FY2026", 1, 2, 5}, "2026-01-26", "2026-02-22"},
+		// the five-week period, across the March DST change.
+		{"2026-02-23", fiscalID{"FY2026", 1, 3, 9}, "2026-02-23", "2026-03-29"},
+		{"2026-03-29", fiscalID{"FY2026", 1, 3, 13}, "2026-02-23", "2026-03-29"},
+		{"2026-03-30", fiscalID{"FY2026", 2, 4, 14}, "2026-03-30", "2026-04-26"},
+		{"2026-09-28", fiscalID{"FY2026", 4, 10, 40}, "2026-09-28", "2026-10-25"},
+		// period 12 takes the 53rd week.
+		{"2026-11-23", fiscalID{"FY2026", 4, 12, 48}, "2026-11-23", "2027-01-03"},
+		{"2027-01-03", fiscalID{"FY2026", 4, 12, 53}, "2026-11-23", "2027-01-03"},
+		{"2027-01-04", fiscalID{"FY2027", 1, 1, 1}, "2027-01-04", "2027-01-31"},
+	}
+	for _, tt := range tests {
+		d := date(tt.date)
+		day := time.Date(d.Year(), d.Month(), d.Day(), 9, 0, 0, 0, loc)
+		if got := fc.id(day); got != tt.id {
+			t.Errorf("id(%s) = %+v, want %+v", tt.date, got, tt.id)
+		}
+		start, end := fc.bounds(periodFiscalPeriod, day)
+		if s, e := start.Format(time.DateOnly), end.Format(time.DateOnly); s != tt.start || e != tt.end {
+			t.Errorf("bounds(%s) = %s..%s, want %s..%s", tt.date, s, e, tt.start, tt.end)
+		}
+	}
+}
+
+func TestFiscalYearLabels(t *testing.T) {
+	tests := []struct {
+		fc   fiscalCalendar
+		date string
+		want string
+	}{
+		// 2026-07-01 is a Wednesday; the year starts on Monday 2026-06-29.
+		{fiscalCalendar{YearStart: "07-01", Pattern: "4-4-5"}, "2026-06-28", "FY2026"},
+		{fiscalCalendar{YearStart: "07-01", Pattern: "4-4-5"}, "2026-06-29", "FY2027"},
+		{fiscalCalendar{YearStart: "07-01", Pattern: "4-4-5", Label: "start"}, "2026-06-29", "FY2026"},
+		{fiscalCalendar{YearStart: "04-01"}, "2026-03-31", "FY2026"},
+		{fiscalCalendar{YearStart: "04-01"}, "2026-04-01", "FY2027"},
+		{fiscalCalendar{YearStart: "01-01"}, "2026-12-31", "FY2026"},
+	}
+	for _, tt := range tests {
+		fc := tt.fc
+		if err := fc.prepare(); err != nil {
+			t.Fatal(err)
+		}
+		if got := fc.id(date(tt.date)).Year; got != tt.want {
+			t.Errorf("%+v: year of %s = %s, want %s", tt.fc, tt.date, got, tt.want)
+		}
+	}
+}
+
+func TestFiscalSkippedMidnight(t *testing.T) {
+	// Chile skips midnight on Sunday 2026-09-06; a fiscal week starting on
+	// Sunday starts at 01:00 that day.
+	loc, err := loadLocation("America/Santiago")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fc := &fiscalCalendar{YearStart: "01-01", Pattern: "4-4-5", WeekStart: "sunday"}
+	if err := fc.prepare(); err != nil {
+		t.Fatal(err)
+	}
+	day := time.Date(2026, time.September, 9, 9, 0, 0, 0, loc)
+	start, end := fc.bounds(periodFiscalWeek, day)
+	if got := start.Format(time.RFC3339); got != "2026-09-06T01:00:00-03:00" {
+		t.Errorf("week start = %s", got)
+	}
+	if got := end.Format(time.DateOnly); got != "2026-09-12" {
+		t.Errorf("week end = %s", got)
+	}
+}
+
+func TestFiscalWeekPeriodBeforeSkippedMidnight(t *testing.T) {
+	// the fiscal week Aug 30 to Sep 5 2026 in Santiago ends at the skipped
+	// midnight of Sep 6.
+	fc := &fiscalCalendar{YearStart: "01-01", Pattern: "4-4-5", WeekStart: "sunday"}
+	if err := fc.prepare(); err != nil {
+		t.Fatal(err)
+	}
+	loc, err := loadLocation("America/Santiago")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tn := &tenant{Fiscal: fc}
+	due := time.Date(2026, time.September, 6, 9, 0, 0, 0, loc)
+	p := tn.periodFor(occurrence{Zone: "America/Santiago", Schedule: fiscalWeekSchedule, Due: due})
+	if got := p.Start.Format(time.RFC3339); got != "2026-08-30T00:00:00-04:00" {
+		t.Errorf("start = %s", got)
+	}
+	if got := p.End.Format(time.RFC3339); got != "2026-09-06T01:00:00-03:00" {
+		t.Errorf("end = %s", got)
+	}
+	if start, end := p.dates(); start != "2026-08-30" || end != "2026-09-05" {
+		t.Errorf("dates = %s..%s, want 2026-08-30..2026-09-05", start, end)
+	}
+}
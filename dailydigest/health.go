@@ -0,0 +1,300 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// healthCheck is the outcome of one self-check. Status is "pass", "fail" or
+// "skip" for checks that do not apply to this configuration.
+type healthCheck struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Detail     string `json:"detail,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// healthStatus is returned to the caller of a {"action":"healthcheck"}
+// invocation. Status is "pass" only when no check failed.
+type healthStatus struct {
+	Status         string        `json:"status"`
+	RunID          string        `json:"run_id"`
+	CatalogVersion string        `json:"catalog_version"`
+	TZDataVersion  string        `json:"tzdata_version"`
+	Checks         []healthCheck `json:"checks"`
+}
+
+// errSkip marks a check that does not apply to the current configuration.
+var errSkip = errors.New("not configured")
+
+// runHealthcheck verifies everything a digest run depends on without
+// sending any digest.
+func runHealthcheck(ctx context.Context) *healthStatus {
+	status := &healthStatus{Status: "pass", RunID: runIDFrom(ctx), CatalogVersion: catalogVersion, TZDataVersion: tzdataVersion}
+	var tenants []*tenant
+	checks := []struct {
+		name string
+		fn   func(context.Context) (string, error)
+	}{
+		{"config", checkConfig},
+		{"tenants", func(ctx context.Context) (string, error) {
+			var err error
+			tenants, err = loadTenants(ctx, cfg)
+			return fmt.Sprintf("%d tenants loaded", len(tenants)), err
+		}},
+		{"catalog", checkCatalog},
+		{"tzdata", checkTZData},
+		{"secrets", func(ctx context.Context) (string, error) { return checkSecrets(ctx, tenants) }},
+		{"state_store", checkStateStore},
+		{"dead_letter_sink", checkDeadLetterSink},
+		{"subscriptions", checkSubscriptions},
+		{"endpoint", func(ctx context.Context) (string, error) { return checkEndpoints(ctx, tenants) }},
+	}
+	for _, c := range checks {
+		start := time.Now()
+		detail, err := c.fn(ctx)
+		hc := healthCheck{Name: c.name, Status: "pass", Detail: detail, DurationMs: time.Since(start).Milliseconds()}
+		switch {
+		case errors.Is(err, errSkip):
+			hc.Status, hc.Detail = "skip", err.Error()
+		case err != nil:
+			hc.Status, hc.Detail = "fail", err.Error()
+			status.Status = "fail"
+		}
+		slog.Info("healthcheck", "check", hc.Name, "outcome", hc.Status, "detail", hc.Detail)
+		status.Checks = append(status.Checks, hc)
+	}
+	return status
+}
+
+// err names the failed checks, or is nil when none failed.
+func (s *healthStatus) err() error {
+	var failed []string
+	for _, c := range s.Checks {
+		if c.Status == "fail" {
+			failed = append(failed, c.Name+": "+c.Detail)
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return fmt.Errorf("healthcheck failed: %s", strings.Join(failed, "; "))
+}
+
+func checkConfig(ctx context.Context) (string, error) {
+	return "", cfg.validate()
+}
+
+// validate catches settings loadConfig accepts but a run would trip over.
+func (c config) validate() error {
+	var errs []error
+	if u, err := url.Parse(c.Endpoint); c.Tenants == "" && (err != nil || !u.IsAbs()) {
+		errs = append(errs, fmt.Errorf("DIGEST_ENDPOINT %q is not an absolute URL", c.Endpoint))
+	}
+	if c.BatchSize < 0 {
+		errs = append(errs, fmt.Errorf("DIGEST_BATCH_SIZE must not be negative"))
+	}
+	if c.FanOut != fanOutZone && c.FanOut != fanOutOffset {
+		errs = append(errs, fmt.Errorf("DIGEST_FANOUT must be zone or offset"))
+	}
+	if c.DeadLetterKind != "" && c.DeadLetterTarget == "" {
+		errs = append(errs, fmt.Errorf("DIGEST_DEADLETTER_TARGET is required with DIGEST_DEADLETTER_KIND"))
+	}
+	if c.StateKind != "" && c.StateTarget == "" {
+		errs = append(errs, fmt.Errorf("DIGEST_STATE_TARGET is required with DIGEST_STATE_KIND"))
+	}
+	if (c.ReportKind == "file" || c.ReportKind == "s3") && c.ReportTarget == "" {
+		errs = append(errs, fmt.Errorf("DIGEST_REPORT_TARGET is required with DIGEST_REPORT_KIND"))
+	}
+	if c.ReportKind == "state" && c.StateKind == "" {
+		errs = append(errs, fmt.Errorf("DIGEST_REPORT_KIND=state needs a state store"))
+	}
+	if (c.TLSCert == "") != (c.TLSKey == "") {
+		errs = append(errs, fmt.Errorf("DIGEST_TLS_CERT and DIGEST_TLS_KEY must be set together"))
+	}
+	if c.OAuthTokenURL != "" && (c.OAuthClientID == "" || c.OAuthClientSecret == "") {
+		errs = append(errs, fmt.Errorf("DIGEST_OAUTH_CLIENT_ID and DIGEST_OAUTH_CLIENT_SECRET are required with DIGEST_OAUTH_TOKEN_URL"))
+	}
+	return errors.Join(errs...)
+}
+
+// checkCatalog loads every catalog zone the way evaluation does, from the
+// embedded tz database first, and checks that each one a work
+// week, holiday or locale could apply to has a country.
+func checkCatalog(ctx context.Context) (string, error) {
+	var unknown, noCountry []string
+	total := 0
+	for _, zones := range timezones {
+		for _, tz := range zones {
+			total++
+			if _, err := loadLocation(tz); err != nil {
+				unknown = append(unknown, tz)
+			}
+			if zoneCountries[tz] == "" && !countrylessZone(tz) {
+				noCountry = append(noCountry, tz)
+			}
+		}
+	}
+	var errs []error
+	if len(unknown) > 0 {
+		errs = append(errs, fmt.Errorf("%d of %d zones do not resolve: %s", len(unknown), total, strings.Join(unknown, ", ")))
+	}
+	if len(noCountry) > 0 {
+		errs = append(errs, fmt.Errorf("%d of %d zones have no country in zone.tab, catalog.tab or backward.tab: %s", len(noCountry), total, strings.Join(noCountry, ", ")))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d zones resolve", total), nil
+}
+
+// checkSecrets reads the shared and per-tenant TLS material and every
+// tenant's OAuth2 client secret. Static body tokens were already read when the tenants loaded.
+func checkSecrets(ctx context.Context, tenants []*tenant) (string, error) {
+	var checked []string
+	if cfg.tlsConfigured() {
+		if err := checkTLS(ctx, httpClient); err != nil {
+			return "", fmt.Errorf("tls: %w", err)
+		}
+		checked = append(checked, "tls")
+	}
+	for _, tn := range tenants {
+		if tn.client == nil {
+			continue
+		}
+		if err := checkTLS(ctx, tn.client); err != nil {
+			return "", fmt.Errorf("tenant %s tls: %w", tn.ID, err)
+		}
+		checked = append(checked, tn.ID+" tls")
+	}
+	for _, tn := range tenants {
+		if tn.OAuth == nil {
+			continue
+		}
+		if _, err := loadSecret(ctx, tn.OAuth.ClientSecret); err != nil {
+			return "", fmt.Errorf("tenant %s oauth client secret: %w", tn.ID, err)
+		}
+		checked = append(checked, tn.ID+" oauth")
+	}
+	if len(checked) == 0 {
+		return "", errSkip
+	}
+	return strings.Join(checked, ", "), nil
+}
+
+func checkTLS(ctx context.Context, d *deliveryClient) error {
+	material, err := d.loadMaterial(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = d.buildTLS(material)
+	return err
+}
+
+func checkStateStore(ctx context.Context) (string, error) {
+	store, err := newStateStore(ctx, cfg)
+	if err != nil {
+		return "", err
+	}
+	if store == nil {
+		return "", errSkip
+	}
+	if _, err := store.Get(ctx, breakerKey(cfg.Endpoint)); err != nil && !errors.Is(err, errNotFound) {
+		return "", err
+	}
+	return cfg.StateKind, nil
+}
+
+func checkDeadLetterSink(ctx context.Context) (string, error) {
+	sink, err := newDeadLetterSink(ctx, cfg)
+	if err != nil {
+		return "", err
+	}
+	if sink == nil {
+		return "", errSkip
+	}
+	return cfg.DeadLetterKind, nil
+}
+
+func checkEndpoints(ctx context.Context, tenants []*tenant) (string, error) {
+	if len(tenants) == 0 {
+		return "", fmt.Errorf("no tenant to check")
+	}
+	var (
+		details []string
+		errs    []error
+	)
+	for _, tn := range tenants {
+		detail, err := checkEndpoint(ctx, tn)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("tenant %s: %w", tn.ID, err))
+			continue
+		}
+		details = append(details, tn.ID+": "+detail)
+	}
+	return strings.Join(details, "; "), errors.Join(errs...)
+}
+
+func checkSubscriptions(ctx context.Context) (string, error) {
+	if cfg.Subscriptions == "" {
+		return "", errSkip
+	}
+	store, err := newStateStore(ctx, cfg)
+	if err != nil {
+		return "", err
+	}
+	subs, err := fetchSubscriptions(ctx, cfg, store)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d subscriptions", len(subs)), nil
+}
+
+// checkEndpoint sends a HEAD to the tenant's endpoint, or a GET to
+// DIGEST_HEALTH_PATH on the same host when one is configured. Any answer
+// below 500 shows the endpoint is up, since a digest receiver may well
+// reject HEAD.
+func checkEndpoint(ctx context.Context, tn *tenant) (string, error) {
+	method, target := http.MethodHead, tn.Endpoint
+	if cfg.HealthPath != "" {
+		u, err := url.Parse(tn.Endpoint)
+		if err != nil {
+			return "", err
+		}
+		ref, err := url.Parse(cfg.HealthPath)
+		if err != nil {
+			return "", err
+		}
+		method, target = http.MethodGet, u.ResolveReference(ref).String()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, nil)
+	if err != nil {
+		return "", err
+	}
+	if tn.tokens.enabled() {
+		access, err := tn.tokens.token(ctx)
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Authorization", "Bearer "+access)
+	}
+	client, err := tn.delivery().get(ctx)
+	if err != nil {
+		return "", err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 500 {
+		return "", &statusError{Code: resp.StatusCode, Status: resp.Status}
+	}
+	return fmt.Sprintf("%s %s: %s", method, target, resp.Status), nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckCatalog(t *testing.T) {
+	if _, err := checkCatalog(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckConfig(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		edit func(c *config)
+		want string
+	}{
+		{"defaults with an endpoint", func(c *config) {}, ""},
+		{"placeholder endpoint", func(c *config) { c.Endpoint = "<YOUR APP ENDPOINT>" }, "DIGEST_ENDPOINT"},
+		{"tenants file instead of endpoint", func(c *config) { c.Endpoint, c.Tenants = "", "file:tenants.json" }, ""},
+		{"negative batch size", func(c *config) { c.BatchSize = -1 }, "DIGEST_BATCH_SIZE"},
+		{"unknown fan-out", func(c *config) { c.FanOut = "region" }, "DIGEST_FANOUT"},
+		{"sink without target", func(c *config) { c.DeadLetterKind = "file" }, "DIGEST_DEADLETTER_TARGET"},
+		{"store without target", func(c *config) { c.StateKind = "s3" }, "DIGEST_STATE_TARGET"},
+		{"report without target", func(c *config) { c.ReportKind = "file" }, "DIGEST_REPORT_TARGET"},
+		{"report in missing store", func(c *config) { c.ReportKind = "state" }, "needs a state store"},
+		{"cert without key", func(c *config) { c.TLSCert = "file:cert.pem" }, "DIGEST_TLS_KEY"},
+		{"oauth without client", func(c *config) { c.OAuthTokenURL = "https://auth.example.com/token" }, "DIGEST_OAUTH_CLIENT_ID"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			useConfig(t, func(c *config) {
+				c.Endpoint = "https://example.com/digest"
+				tt.edit(c)
+			})
+			_, err := checkConfig(t.Context())
+			if tt.want == "" && err != nil {
+				t.Errorf("checkConfig = %v, want no error", err)
+			}
+			if tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)) {
+				t.Errorf("checkConfig = %v, want it to mention %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckSecrets(t *testing.T) {
+	useConfig(t, func(c *config) {})
+	secret := filepath.Join(t.TempDir(), "client-secret")
+	if err := os.WriteFile(secret, []byte("s3cr3t"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	plain := &tenant{ID: "plain"}
+	withOAuth := &tenant{ID: "acme", OAuth: &oauthSettings{ClientSecret: "file:" + secret}}
+	missing := &tenant{ID: "globex", OAuth: &oauthSettings{ClientSecret: "file:" + secret + ".missing"}}
+
+	if _, err := checkSecrets(t.Context(), []*tenant{plain}); !errors.Is(err, errSkip) {
+		t.Errorf("without secrets checkSecrets = %v, want a skip", err)
+	}
+	if detail, err := checkSecrets(t.Context(), []*tenant{plain, withOAuth}); err != nil || detail != "acme oauth" {
+		t.Errorf("checkSecrets = %q, %v", detail, err)
+	}
+	if _, err := checkSecrets(t.Context(), []*tenant{withOAuth, missing}); err == nil || !strings.Contains(err.Error(), "tenant globex") {
+		t.Errorf("unreadable secret gave %v, want it to name the tenant", err)
+	}
+
+	useConfig(t, func(c *config) { c.TLSCert, c.TLSKey = "file:"+secret+".missing", "file:"+secret })
+	if _, err := checkSecrets(t.Context(), nil); err == nil || !strings.HasPrefix(err.Error(), "tls: ") {
+		t.Errorf("missing client certificate gave %v", err)
+	}
+}
+
+func TestHealthcheckAction(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}))
+	defer srv.Close()
+	statuses := func(s *healthStatus) map[string]string {
+		out := map[string]string{}
+		for _, c := range s.Checks {
+			out[c.Name] = c.Status
+		}
+		return out
+	}
+
+	useConfig(t, func(c *config) { c.Endpoint = srv.URL })
+	result, err := runCron(t.Context(), json.RawMessage(`{"action": "healthcheck"}`))
+	if err != nil {
+		t.Fatalf("healthy healthcheck failed: %v", err)
+	}
+	status := result.(*healthStatus)
+	got := statuses(status)
+	if status.Status != "pass" || got["config"] != "pass" || got["catalog"] != "pass" || got["endpoint"] != "pass" {
+		t.Errorf("healthy checks = %v, status %s", got, status.Status)
+	}
+	for _, name := range []string{"secrets", "state_store", "dead_letter_sink", "subscriptions"} {
+		if got[name] != "skip" {
+			t.Errorf("%s = %s, want skip when not configured", name, got[name])
+		}
+	}
+
+	srv.Close()
+	useConfig(t, func(c *config) { c.Endpoint, c.BatchSize = srv.URL, -1 })
+	result, err = runCron(t.Context(), json.RawMessage(`{"action": "healthcheck"}`))
+	status, _ = result.(*healthStatus)
+	if status == nil || status.Status != "fail" {
+		t.Fatalf("failing healthcheck returned %+v", result)
+	}
+	if got := statuses(status); got["config"] != "fail" || got["endpoint"] != "fail" || got["catalog"] != "pass" {
+		t.Errorf("failing checks = %v", got)
+	}
+	if err == nil || !strings.Contains(err.Error(), "config: DIGEST_BATCH_SIZE") || !strings.Contains(err.Error(), "endpoint: ") {
+		t.Errorf("failing healthcheck error = %v, want it to name config and endpoint", err)
+	}
+}
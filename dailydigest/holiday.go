@@ -0,0 +1,449 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	holidaySkip  = "skip"
+	holidayShift = "next_business_day"
+	holidaySend  = "send"
+)
+
+// holidaySettings is the document DIGEST_HOLIDAYS points to. Calendars names
+// each calendar source: "builtin:<country>" or a secret reference to an
+// iCalendar file. Zones maps an IANA zone, a catalog abbreviation, an ISO
+// country code or "*" to calendar names; a source can be used directly in
+// place of a name. Policies says per CType what happens on a holiday.
+type holidaySettings struct {
+	Calendars map[string]string   `json:"calendars"`
+	Zones     map[string][]string `json:"zones"`
+	Policies  map[CType]string    `json:"policies"`
+}
+
+// holidayCalendar answers whether a local date is a holiday, and which.
+type holidayCalendar interface {
+	holiday(date time.Time) (string, bool)
+}
+
+// holidays is the loaded form of holidaySettings.
+type holidays struct {
+	zones    map[string][]holidayCalendar
+	policies map[CType]string
+}
+
+// loadHolidays reads DIGEST_HOLIDAYS and every calendar it names.
+func loadHolidays(ctx context.Context, c config) (*holidays, error) {
+	if c.Holidays == "" {
+		return nil, nil
+	}
+	data, err := loadSecret(ctx, c.Holidays)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read holidays: %w", err)
+	}
+	var hs holidaySettings
+	if err := json.Unmarshal(data, &hs); err != nil {
+		return nil, fmt.Errorf("cannot decode holidays: %w", err)
+	}
+	if err := checkHolidayPolicies(hs.Policies); err != nil {
+		return nil, err
+	}
+	loaded := map[string]holidayCalendar{}
+	h := &holidays{zones: map[string][]holidayCalendar{}, policies: hs.Policies}
+	for key, names := range hs.Zones {
+		for _, name := range names {
+			source, ok := hs.Calendars[name]
+			if !ok {
+				source = name
+			}
+			cal, ok := loaded[source]
+			if !ok {
+				if cal, err = loadHolidayCalendar(ctx, source); err != nil {
+					return nil, fmt.Errorf("holiday calendar %s: %w", name, err)
+				}
+				loaded[source] = cal
+			}
+			h.zones[key] = append(h.zones[key], cal)
+		}
+	}
+	return h, nil
+}
+
+func checkHolidayPolicies(policies map[CType]string) error {
+	for cType, p := range policies {
+		switch p {
+		case holidaySkip, holidayShift, holidaySend:
+		default:
+			return fmt.Errorf("unknown holiday policy %q for %s", p, cType)
+		}
+	}
+	return nil
+}
+
+func loadHolidayCalendar(ctx context.Context, source string) (holidayCalendar, error) {
+	if country, ok := strings.CutPrefix(source, "builtin:"); ok {
+		rules, ok := builtinHolidays[strings.ToUpper(country)]
+		if !ok {
+			return nil, fmt.Errorf("no built-in holidays for %q", country)
+		}
+		return &ruleCalendar{rules: rules, years: map[int]bool{}, days: map[string]string{}}, nil
+	}
+	data, err := loadSecret(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+	return parseICS(string(data))
+}
+
+// calendarsFor returns the calendars that apply to zone, found under group.
+func (h *holidays) calendarsFor(group, zone string) []holidayCalendar {
+	var out []holidayCalendar
+	for _, key := range []string{zone, group, zoneCountries[zone], "*"} {
+		if key != "" {
+			out = append(out, h.zones[key]...)
+		}
+	}
+	return out
+}
+
+// holidayPolicy is the tenant's policy for cType, then the global one, then
+// skip.
+func (tn *tenant) holidayPolicy(cType CType) string {
+	if p, ok := tn.HolidayPolicies[cType]; ok {
+		return p
+	}
+	if p, ok := tn.holidays.policies[cType]; ok {
+		return p
+	}
+	return holidaySkip
+}
+
+// icsCalendar holds the all-day events of an iCalendar file. Events with a
+// FREQ=YEARLY rule repeat on the same date every year, including rules whose
+// BYMONTH and BYMONTHDAY only restate DTSTART; other recurrence rules are
+// not expanded.
+type icsCalendar struct {
+	dates  map[string]string // 2006-01-02
+	yearly map[string]string // 01-02
+}
+
+func (c *icsCalendar) holiday(date time.Time) (string, bool) {
+	d := date.Format(time.DateOnly)
+	if name, ok := c.dates[d]; ok {
+		return name, true
+	}
+	name, ok := c.yearly[d[5:]]
+	return name, ok
+}
+
+func parseICS(data string) (*icsCalendar, error) {
+	// unfold continuation lines first.
+	data = strings.NewReplacer("\r\n ", "", "\r\n\t", "", "\n ", "", "\n\t", "").Replace(data)
+	c := &icsCalendar{dates: map[string]string{}, yearly: map[string]string{}}
+	var (
+		inEvent    bool
+		start, end time.Time
+		summary    string
+		rrule      string
+	)
+	sc := bufio.NewScanner(strings.NewReader(data))
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), "\r")
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		name, _, _ = strings.Cut(name, ";")
+		switch strings.ToUpper(name) {
+		case "BEGIN":
+			if value == "VEVENT" {
+				inEvent, start, end, summary, rrule = true, time.Time{}, time.Time{}, "", ""
+			}
+		case "DTSTART":
+			start, _ = parseICSDate(value)
+		case "DTEND":
+			end, _ = parseICSDate(value)
+		case "SUMMARY":
+			summary = value
+		case "RRULE":
+			rrule = value
+		case "END":
+			if value != "VEVENT" || !inEvent {
+				continue
+			}
+			inEvent = false
+			if start.IsZero() {
+				return nil, fmt.Errorf("event %q has no usable DTSTART", summary)
+			}
+			if !end.After(start) {
+				end = start.AddDate(0, 0, 1)
+			}
+			yearly := sameDateYearly(rrule, start)
+			for d := start; d.Before(end); d = d.AddDate(0, 0, 1) {
+				if yearly {
+					c.yearly[d.Format("01-02")] = summary
+				} else {
+					c.dates[d.Format(time.DateOnly)] = summary
+				}
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// sameDateYearly reports whether rrule repeats an event on start's month and
+// day every year.
+func sameDateYearly(rrule string, start time.Time) bool {
+	if rrule == "" {
+		return false
+	}
+	yearly := false
+	for _, part := range strings.Split(rrule, ";") {
+		key, value, _ := strings.Cut(part, "=")
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			yearly = strings.EqualFold(value, "YEARLY")
+		case "INTERVAL":
+			if n, err := strconv.Atoi(value); err != nil || n != 1 {
+				return false
+			}
+		case "BYMONTH":
+			if n, err := strconv.Atoi(value); err != nil || n != int(start.Month()) {
+				return false
+			}
+		case "BYMONTHDAY":
+			if n, err := strconv.Atoi(value); err != nil || n != start.Day() {
+				return false
+			}
+		case "WKST":
+		default:
+			return false
+		}
+	}
+	return yearly
+}
+
+func parseICSDate(v string) (time.Time, error) {
+	if len(v) < 8 {
+		return time.Time{}, fmt.Errorf("bad date %q", v)
+	}
+	return time.Parse("20060102", v[:8])
+}
+
+// holidayRule is one public holiday: a fixed date, the nth weekday of a month
+// counted from Day (negative counts back from Day, or from the month's end),
+// or a day relative to Easter Sunday. Observed moves a date that falls on a
+// weekend: "monday" to the next free weekday, "nearest" Saturday to Friday
+// and Sunday to Monday.
+type holidayRule struct {
+	Name     string
+	Month    time.Month
+	Day      int
+	Nth      int
+	Weekday  time.Weekday
+	Easter   bool
+	Offset   int
+	Observed string
+}
+
+func (h holidayRule) date(year int) time.Time {
+	if h.Easter {
+		return easterSunday(year).AddDate(0, 0, h.Offset)
+	}
+	if h.Nth == 0 {
+		return time.Date(year, h.Month, h.Day, 0, 0, 0, 0, time.UTC)
+	}
+	if h.Nth > 0 {
+		d := time.Date(year, h.Month, max(h.Day, 1), 0, 0, 0, 0, time.UTC)
+		d = d.AddDate(0, 0, (int(h.Weekday)-int(d.Weekday())+7)%7)
+		return d.AddDate(0, 0, 7*(h.Nth-1))
+	}
+	d := time.Date(year, h.Month+1, 0, 0, 0, 0, 0, time.UTC)
+	if h.Day > 0 {
+		d = time.Date(year, h.Month, h.Day, 0, 0, 0, 0, time.UTC)
+	}
+	d = d.AddDate(0, 0, -((int(d.Weekday()) - int(h.Weekday) + 7) % 7))
+	return d.AddDate(0, 0, 7*(h.Nth+1))
+}
+
+// easterSunday is the Gregorian Easter date (anonymous algorithm).
+func easterSunday(year int) time.Time {
+	a, b, c := year%19, year/100, year%100
+	d, e := b/4, b%4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i, k := c/4, c%4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+}
+
+// ruleCalendar computes a built-in rule set one year at a time. A date's
+// neighbouring years are expanded with it, since an observed day can fall
+// in the year before or after its holiday, e.g. New Year's Day on a
+// Saturday observed on December 31.
+type ruleCalendar struct {
+	rules []holidayRule
+	mu    sync.Mutex
+	years map[int]bool
+	days  map[string]string // 2006-01-02
+}
+
+func (c *ruleCalendar) holiday(date time.Time) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for y := date.Year() - 1; y <= date.Year()+1; y++ {
+		if !c.years[y] {
+			c.years[y] = true
+			c.expand(y)
+		}
+	}
+	name, ok := c.days[date.Format(time.DateOnly)]
+	return name, ok
+}
+
+// expand adds year's holidays to c.days. An observed day never replaces an
+// actual holiday, whichever year is expanded first.
+func (c *ruleCalendar) expand(year int) {
+	days := c.days
+	observe := func(d time.Time, name string) {
+		if _, ok := days[d.Format(time.DateOnly)]; !ok {
+			days[d.Format(time.DateOnly)] = name + " (observed)"
+		}
+	}
+	// observed days move past every actual holiday date.
+	taken := map[string]bool{}
+	for _, r := range c.rules {
+		taken[r.date(year).Format(time.DateOnly)] = true
+	}
+	for _, r := range c.rules {
+		d := r.date(year)
+		days[d.Format(time.DateOnly)] = r.Name
+		weekend := d.Weekday() == time.Saturday || d.Weekday() == time.Sunday
+		switch {
+		case !weekend || r.Observed == "":
+		case r.Observed == "nearest" && d.Weekday() == time.Saturday:
+			observe(d.AddDate(0, 0, -1), r.Name)
+		default:
+			for d.Weekday() == time.Saturday || d.Weekday() == time.Sunday || taken[d.Format(time.DateOnly)] {
+				d = d.AddDate(0, 0, 1)
+			}
+			taken[d.Format(time.DateOnly)] = true
+			observe(d, r.Name)
+		}
+	}
+}
+
+// builtinHolidays covers national public holidays with fixed or computable
+// dates. Holidays that follow lunar or other calendars are not included;
+// use an iCalendar file for those.
+var builtinHolidays = map[string][]holidayRule{
+	"US": {
+		{Name: "New Year's Day", Month: time.January, Day: 1, Observed: "nearest"},
+		{Name: "Martin Luther King Jr. Day", Month: time.January, Nth: 3, Weekday: time.Monday},
+		{Name: "Washington's Birthday", Month: time.February, Nth: 3, Weekday: time.Monday},
+		{Name: "Memorial Day", Month: time.May, Nth: -1, Weekday: time.Monday},
+		{Name: "Juneteenth", Month: time.June, Day: 19, Observed: "nearest"},
+		{Name: "Independence Day", Month: time.July, Day: 4, Observed: "nearest"},
+		{Name: "Labor Day", Month: time.September, Nth: 1, Weekday: time.Monday},
+		{Name: "Columbus Day", Month: time.October, Nth: 2, Weekday: time.Monday},
+		{Name: "Veterans Day", Month: time.November, Day: 11, Observed: "nearest"},
+		{Name: "Thanksgiving Day", Month: time.November, Nth: 4, Weekday: time.Thursday},
+		{Name: "Christmas Day", Month: time.December, Day: 25, Observed: "nearest"},
+	},
+	"CA": {
+		{Name: "New Year's Day", Month: time.January, Day: 1, Observed: "monday"},
+		{Name: "Good Friday", Easter: true, Offset: -2},
+		{Name: "Victoria Day", Month: time.May, Day: 24, Nth: -1, Weekday: time.Monday},
+		{Name: "Canada Day", Month: time.July, Day: 1, Observed: "monday"},
+		{Name: "Labour Day", Month: time.September, Nth: 1, Weekday: time.Monday},
+		{Name: "Thanksgiving", Month: time.October, Nth: 2, Weekday: time.Monday},
+		{Name: "Christmas Day", Month: time.December, Day: 25, Observed: "monday"},
+		{Name: "Boxing Day", Month: time.December, Day: 26, Observed: "monday"},
+	},
+	"GB": {
+		{Name: "New Year's Day", Month: time.January, Day: 1, Observed: "monday"},
+		{Name: "Good Friday", Easter: true, Offset: -2},
+		{Name: "Easter Monday", Easter: true, Offset: 1},
+		{Name: "Early May Bank Holiday", Month: time.May, Nth: 1, Weekday: time.Monday},
+		{Name: "Spring Bank Holiday", Month: time.May, Nth: -1, Weekday: time.Monday},
+		{Name: "Summer Bank Holiday", Month: time.August, Nth: -1, Weekday: time.Monday},
+		{Name: "Christmas Day", Month: time.December, Day: 25, Observed: "monday"},
+		{Name: "Boxing Day", Month: time.December, Day: 26, Observed: "monday"},
+	},
+	"IE": {
+		{Name: "New Year's Day", Month: time.January, Day: 1, Observed: "monday"},
+		{Name: "St Brigid's Day", Month: time.February, Nth: 1, Weekday: time.Monday},
+		{Name: "St Patrick's Day", Month: time.March, Day: 17, Observed: "monday"},
+		{Name: "Easter Monday", Easter: true, Offset: 1},
+		{Name: "May Bank Holiday", Month: time.May, Nth: 1, Weekday: time.Monday},
+		{Name: "June Bank Holiday", Month: time.June, Nth: 1, Weekday: time.Monday},
+		{Name: "August Bank Holiday", Month: time.August, Nth: 1, Weekday: time.Monday},
+		{Name: "October Bank Holiday", Month: time.October, Nth: -1, Weekday: time.Monday},
+		{Name: "Christmas Day", Month: time.December, Day: 25, Observed: "monday"},
+		{Name: "St Stephen's Day", Month: time.December, Day: 26, Observed: "monday"},
+	},
+	"DE": {
+		{Name: "Neujahr", Month: time.January, Day: 1},
+		{Name: "Karfreitag", Easter: true, Offset: -2},
+		{Name: "Ostermontag", Easter: true, Offset: 1},
+		{Name: "Tag der Arbeit", Month: time.May, Day: 1},
+		{Name: "Christi Himmelfahrt", Easter: true, Offset: 39},
+		{Name: "Pfingstmontag", Easter: true, Offset: 50},
+		{Name: "Tag der Deutschen Einheit", Month: time.October, Day: 3},
+		{Name: "1. Weihnachtstag", Month: time.December, Day: 25},
+		{Name: "2. Weihnachtstag", Month: time.December, Day: 26},
+	},
+	"FR": {
+		{Name: "Jour de l'an", Month: time.January, Day: 1},
+		{Name: "Lundi de Pâques", Easter: true, Offset: 1},
+		{Name: "Fête du Travail", Month: time.May, Day: 1},
+		{Name: "Victoire 1945", Month: time.May, Day: 8},
+		{Name: "Ascension", Easter: true, Offset: 39},
+		{Name: "Lundi de Pentecôte", Easter: true, Offset: 50},
+		{Name: "Fête nationale", Month: time.July, Day: 14},
+		{Name: "Assomption", Month: time.August, Day: 15},
+		{Name: "Toussaint", Month: time.November, Day: 1},
+		{Name: "Armistice 1918", Month: time.November, Day: 11},
+		{Name: "Noël", Month: time.December, Day: 25},
+	},
+	"AU": {
+		{Name: "New Year's Day", Month: time.January, Day: 1, Observed: "monday"},
+		{Name: "Australia Day", Month: time.January, Day: 26, Observed: "monday"},
+		{Name: "Good Friday", Easter: true, Offset: -2},
+		{Name: "Easter Monday", Easter: true, Offset: 1},
+		{Name: "Anzac Day", Month: time.April, Day: 25},
+		{Name: "Christmas Day", Month: time.December, Day: 25, Observed: "monday"},
+		{Name: "Boxing Day", Month: time.December, Day: 26, Observed: "monday"},
+	},
+	"NZ": {
+		{Name: "New Year's Day", Month: time.January, Day: 1, Observed: "monday"},
+		{Name: "Day after New Year's Day", Month: time.January, Day: 2, Observed: "monday"},
+		{Name: "Waitangi Day", Month: time.February, Day: 6, Observed: "monday"},
+		{Name: "Good Friday", Easter: true, Offset: -2},
+		{Name: "Easter Monday", Easter: true, Offset: 1},
+		{Name: "Anzac Day", Month: time.April, Day: 25, Observed: "monday"},
+		{Name: "King's Birthday", Month: time.June, Nth: 1, Weekday: time.Monday},
+		{Name: "Labour Day", Month: time.October, Nth: 4, Weekday: time.Monday},
+		{Name: "Christmas Day", Month: time.December, Day: 25, Observed: "monday"},
+		{Name: "Boxing Day", Month: time.December, Day: 26, Observed: "monday"},
+	},
+	"IN": {
+		{Name: "Republic Day", Month: time.January, Day: 26},
+		{Name: "Independence Day", Month: time.August, Day: 15},
+		{Name: "Gandhi Jayanti", Month: time.October, Day: 2},
+	},
+}
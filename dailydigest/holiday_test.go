@@ -0,0 +1,123 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	d, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func TestRuleCalendarHoliday(t *testing.T) {
+	tests := []struct {
+		country string
+		date    string
+		want    string // "" for a working day
+	}{
+		// New Year's Day 2028 is a Saturday, observed in the previous year.
+		{"US", "2027-12-31", "New Year's Day (observed)"},
+		{"US", "2028-01-01", "New Year's Day"},
+		{"US", "2028-01-03", ""},
+		// July 4 2026 is a Saturday, observed on the Friday.
+		{"US", "2026-07-03", "Independence Day (observed)"},
+		{"US", "2026-07-04", "Independence Day"},
+		{"US", "2026-11-26", "Thanksgiving Day"},
+		{"US", "2026-05-25", "Memorial Day"},
+		// Christmas 2027 is a Saturday: Christmas moves to Monday 27, Boxing
+		// Day past it to Tuesday 28.
+		{"GB", "2027-12-27", "Christmas Day (observed)"},
+		{"GB", "2027-12-28", "Boxing Day (observed)"},
+		// New Year's Day 2028 on a Saturday moves to Monday 3.
+		{"GB", "2028-01-03", "New Year's Day (observed)"},
+		{"GB", "2027-12-31", ""},
+		// Easter 2026 is April 5, 2027 March 28, 2028 April 16.
+		{"GB", "2026-04-03", "Good Friday"},
+		{"GB", "2026-04-06", "Easter Monday"},
+		{"DE", "2027-03-26", "Karfreitag"},
+		{"DE", "2027-05-06", "Christi Himmelfahrt"},
+		{"FR", "2028-06-05", "Lundi de Pentecôte"},
+		{"AU", "2028-04-14", "Good Friday"},
+		// Victoria Day: the Monday before May 25.
+		{"CA", "2026-05-18", "Victoria Day"},
+		{"CA", "2027-05-24", "Victoria Day"},
+		// DE holidays are not observed.
+		{"DE", "2027-12-27", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.country+"/"+tt.date, func(t *testing.T) {
+			cal, err := loadHolidayCalendar(t.Context(), "builtin:"+tt.country)
+			if err != nil {
+				t.Fatal(err)
+			}
+			name, ok := cal.holiday(date(tt.date))
+			if ok != (tt.want != "") || name != tt.want {
+				t.Errorf("holiday(%s) = %q, %v, want %q", tt.date, name, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestEasterSunday(t *testing.T) {
+	for year, want := range map[int]string{
+		2024: "2024-03-31", 2025: "2025-04-20", 2026: "2026-04-05",
+		2027: "2027-03-28", 2028: "2028-04-16", 2038: "2038-04-25", 2285: "2285-03-22",
+	} {
+		if got := easterSunday(year).Format(time.DateOnly); got != want {
+			t.Errorf("easterSunday(%d) = %s, want %s", year, got, want)
+		}
+	}
+}
+
+func TestParseICS(t *testing.T) {
+	cal, err := parseICS(`BEGIN:VCALENDAR
+BEGIN:VEVENT
+SUMMARY:Independence Day
+RRULE:FREQ=YEARLY;BYMONTH=7;BYMONTHDAY=4
+DTSTART;VALUE=DATE:20250704
+END:VEVENT
+BEGIN:VEVENT
+DTSTART;VALUE=DATE:20250101
+SUMMARY:New Year
+RRULE:FREQ=YEARLY
+END:VEVENT
+BEGIN:VEVENT
+DTSTART;VALUE=DATE:20251127
+SUMMARY:Thanksgiving
+RRULE:FREQ=YEARLY;BYMONTH=11;BYDAY=4TH
+END:VEVENT
+BEGIN:VEVENT
+DTSTART;VALUE=DATE:20261224
+DTEND;VALUE=DATE:20261227
+SUMMARY:Winter break
+END:VEVENT
+END:VCALENDAR
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		date string
+		want string
+	}{
+		{"2025-07-04", "Independence Day"},
+		{"2026-07-04", "Independence Day"},
+		{"2030-01-01", "New Year"},
+		{"2025-11-27", "Thanksgiving"},
+		// BYDAY is not expanded, so only the first occurrence counts.
+		{"2026-11-26", ""},
+		{"2026-12-24", "Winter break"},
+		{"2026-12-26", "Winter break"},
+		{"2026-12-27", ""},
+	}
+	for _, tt := range tests {
+		name, ok := cal.holiday(date(tt.date))
+		if ok != (tt.want != "") || name != tt.want {
+			t.Errorf("holiday(%s) = %q, %v, want %q", tt.date, name, ok, tt.want)
+		}
+	}
+}
@@ -5,86 +5,325 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
+	"os"
 	"time"
 
 	"github.com/aws/aws-lambda-go/lambda"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type CType string
 
 const (
-	TypeDailyAt4P  CType = "daily_at_4P"
-	TypeWeeklyAt9A CType = "weekly_at_9A"
+	TypeDailyAt4P     CType = "daily_at_4P"
+	TypeWeeklyAt9A    CType = "weekly_at_9A"
+	TypeMonthlyAt9A   CType = "monthly_at_9A"
+	TypeQuarterlyAt9A CType = "quarterly_at_9A"
+	TypeYearlyAt9A    CType = "yearly_at_9A"
+
+	TypeFiscalPeriodAt9A CType = "fiscal_period_at_9A"
+	TypeFiscalWeekAt9A   CType = "fiscal_week_at_9A"
 )
 
 const (
 	triggerFrequency = 15
 )
 
+var cfg = loadConfig()
+
 func main() {
+	logTZData()
 	lambda.Start(runCron)
 }
 
-func runCron(ctx context.Context, event interface{}) error {
-	fmt.Printf("received event: %+v\n", event)
-	return runDigest()
+// cronEvent is the subset of the invocation payload the handler looks at.
+// Scheduled EventBridge events carry no action and run the digest; other
+// actions are "redrive", "healthcheck", "dryrun" (evaluate At without
+// sending anything) and "simulate" (dry-run every Step from From to To).
+type cronEvent struct {
+	Action string    `json:"action"`
+	At     time.Time `json:"at"`
+	From   time.Time `json:"from"`
+	To     time.Time `json:"to"`
+	Step   string    `json:"step"`
+}
+
+// runCron returns a result only for actions that have one to report, such as
+// the healthcheck status, which comes back alongside the error when a check
+// failed.
+func runCron(ctx context.Context, event json.RawMessage) (result interface{}, err error) {
+	ctx, runID := startRun(ctx)
+	slog.Info("received event", "event", event)
+	var ev cronEvent
+	_ = json.Unmarshal(event, &ev)
+
+	ctx, span := tracer.Start(ctx, "digest.invocation", trace.WithAttributes(
+		attribute.String("digest.run_id", runID),
+		attribute.String("digest.action", ev.Action),
+	))
+	defer func() {
+		endSpan(span, err)
+		flushTraces(ctx)
+	}()
+
+	switch ev.Action {
+	case "":
+		return nil, runDigest(ctx, time.Now().UTC())
+	case "redrive":
+		return nil, runRedrive(ctx)
+	case "healthcheck":
+		status := runHealthcheck(ctx)
+		return status, status.err()
+	case "dryrun":
+		at := ev.At
+		if at.IsZero() {
+			at = time.Now()
+		}
+		return runDryRun(ctx, at, at, 0)
+	case "simulate":
+		return runSimulation(ctx, ev)
+	default:
+		return nil, fmt.Errorf("unknown action %q", ev.Action)
+	}
+}
+
+// runDigest evaluates every tenant at t. Failed deliveries have already been
+// retried and dead-lettered, so they are logged and reported but do not fail
+// the invocation: EventBridge would retry it inside the same match window and
+// send every digest that did go out a second time. Only a run that could not
+// load a single tenant, and so sent nothing, returns an error.
+func runDigest(ctx context.Context, t time.Time) error {
+	start := time.Now()
+	tenants, loadErr := loadTenants(ctx, cfg)
+	if len(tenants) == 0 && loadErr != nil {
+		return loadErr
+	}
+	if loadErr != nil {
+		slog.Error("some tenants could not be loaded", "error", loadErr, "outcome", "tenant_error")
+	}
+	base := newDigestRun(ctx, cfg)
+	base.at = t
+	base.report = newRunReport(ctx, runIDFrom(ctx), t, tenants)
+	base.subs = loadSubscriptions(ctx, cfg, base.store)
+	var (
+		total         int
+		failedTenants []string
+	)
+	for _, tn := range tenants {
+		run := base.forTenant(ctx, tn)
+		err := evaluateTenant(ctx, t, run)
+		failed := run.failed()
+		total += len(run.results)
+		slog.Info("tenant finished", "tenant", tn.ID, "triggered", len(run.results), "failed", len(failed))
+		if err != nil || len(failed) > 0 {
+			failedTenants = append(failedTenants, tn.ID)
+		}
+	}
+	base.metrics.emit(os.Stdout, time.Since(start))
+	saveReport(ctx, cfg, base.store, base.report)
+	slog.Info("run finished", "evaluated_at", t, "tenants", len(tenants), "triggered", total, "failed_tenants", failedTenants)
+	if len(failedTenants) > 0 {
+		slog.Error("digests failed for some tenants", "failed_tenants", failedTenants, "outcome", "tenant_error")
+	}
+	return nil
 }
 
-func runDigest() error {
-	t := time.Now().UTC()
-	for _, v := range timezones {
-		fmt.Println("checking timzone:", v)
+// evaluateTenant fires every schedule of run's tenant that matches t. A panic
+// is turned into an error so that one tenant cannot take the others down.
+func evaluateTenant(ctx context.Context, t time.Time, run *digestRun) (err error) {
+	tn := run.tenant
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("tenant %s: %v", tn.ID, p)
+			slog.Error("tenant evaluation panicked", "tenant", tn.ID, "error", err, "outcome", "tenant_error")
+		}
+	}()
+	daily, dailyOn := tn.schedule(TypeDailyAt4P)
+	weekly, weeklyOn := tn.schedule(TypeWeeklyAt9A)
+	var periods []schedule
+	for _, ct := range periodTypes {
+		if s, ok := tn.schedule(ct); ok {
+			periods = append(periods, s)
+		}
+	}
+	for abbr, v := range timezones {
+		slog.Debug("checking timezone", "tenant", tn.ID, "group", abbr)
+		gctx, span := tracer.Start(ctx, "digest.evaluate", trace.WithAttributes(
+			attribute.String("digest.tenant", tn.ID),
+			attribute.String("digest.group", abbr),
+		))
 		triggered := false
 		for _, tz := range v {
-			loc, err := time.LoadLocation(tz)
+			if !tn.includes(abbr, tz) || !run.subs.has(tn.ID, tz) {
+				continue
+			}
+			loc, err := loadLocation(tz)
 			if err != nil {
-				fmt.Println("golang's timezone pkg unknown location --> ", tz)
+				slog.Warn("golang's timezone pkg unknown location", "tenant", tn.ID, "group", abbr, "zone", tz, "outcome", "unknown_location")
+				run.metrics.count("", abbr, "skipped")
 				continue
 			}
 			tLoc := t.In(loc)
-			if tLoc.Hour() == 16 && tLoc.Minute() >= 0 && tLoc.Minute() <= triggerFrequency {
-				triggered = true
-				sendDailyDigest(tz)
-			} else if tLoc.Hour() == 9 && tLoc.Minute() >= 0 && tLoc.Minute() <= triggerFrequency && tLoc.Weekday() == time.Monday {
-				triggered = true
-				sendWeeklyDigest(tz)
-			} else {
-				//fmt.Println("no match. not triggered")
+			quiet := tn.quietWindowsFor(abbr, tz)
+			if dailyOn {
+				for _, s := range run.subs.schedulesFor(tn.ID, tz, daily) {
+					if fire(gctx, run, tLoc, occurrence{Group: abbr, Zone: tz, Schedule: s}, quiet, sendDailyDigest) {
+						triggered = true
+					}
+				}
+			}
+			if weeklyOn {
+				for _, s := range run.subs.schedulesFor(tn.ID, tz, weekly) {
+					if fire(gctx, run, tLoc, occurrence{Group: abbr, Zone: tz, Schedule: s}, quiet, sendWeeklyDigest) {
+						triggered = true
+					}
+				}
+			}
+			for _, p := range periods {
+				for _, s := range run.subs.schedulesFor(tn.ID, tz, p) {
+					if fire(gctx, run, tLoc, occurrence{Group: abbr, Zone: tz, Schedule: s}, quiet, sendPeriodDigest) {
+						triggered = true
+					}
+				}
 			}
 		}
 		if !triggered {
-			fmt.Println("no match. not triggered")
+			slog.Debug("no match. not triggered", "tenant", tn.ID, "group", abbr, "outcome", "no_match")
 		}
+		span.SetAttributes(attribute.Bool("digest.triggered", triggered))
+		span.End()
 	}
+	run.flush(ctx)
 	return nil
 }
 
-func sendDailyDigest(zone string) {
-	fmt.Println("triggered: ", fmt.Sprintf("daily, %v", zone))
-	post(zone, TypeDailyAt4P)
+// fire resolves a solar schedule for the day, runs occ through the tenant's
+// holiday calendars and the zone's quiet windows, and sends whatever is due
+// now.
+func fire(ctx context.Context, run *digestRun, tLoc time.Time, occ occurrence, windows []quietWindow,
+	send func(context.Context, *digestRun, occurrence)) bool {
+	if occ.Schedule.Solar != nil {
+		s, now, sup := solarSchedule(occ.Schedule, occ.Zone, tLoc)
+		if sup != nil {
+			run.suppress(occ, sup)
+		}
+		if !now {
+			return false
+		}
+		occ.Schedule = s
+	}
+	d := applyQuietHours(tLoc, occ.Schedule, windows, run.tenant.due(occ))
+	if d.Suppressed != nil {
+		run.suppress(occ, d.Suppressed)
+	}
+	if !d.Fire {
+		return false
+	}
+	occ.Due, occ.DeferredFrom, occ.ShiftedFrom = d.Due, d.DeferredFrom, d.ShiftedFrom
+	occ.Period = run.tenant.periodFor(occ)
+	send(ctx, run, occ)
+	return true
+}
+
+func sendDailyDigest(ctx context.Context, run *digestRun, occ occurrence) {
+	slog.Info("triggered", "tenant", run.tenant.ID, "group", occ.Group, "zone", occ.Zone, "ctype", occ.cType(), "local_time", occ.Schedule.localTime(), "outcome", "triggered")
+	run.trigger(ctx, occ)
 }
 
-func sendWeeklyDigest(zone string) {
-	fmt.Println("triggered: ", fmt.Sprintf("weekly, %v", zone))
-	post(zone, TypeWeeklyAt9A)
+func sendWeeklyDigest(ctx context.Context, run *digestRun, occ occurrence) {
+	slog.Info("triggered", "tenant", run.tenant.ID, "group", occ.Group, "zone", occ.Zone, "ctype", occ.cType(), "local_time", occ.Schedule.localTime(), "outcome", "triggered")
+	run.trigger(ctx, occ)
 }
 
-func post(zone string, cType CType) {
-	postBody, _ := json.Marshal(map[string]string{
-		"zone":  zone,
-		"type":  string(cType),
-		"token": "<TOKEN>",
-	})
-	reqBody := bytes.NewBuffer(postBody)
-	resp, err := http.Post("<YOUR APP ENDPOINT>", "application/json", reqBody)
+func sendPeriodDigest(ctx context.Context, run *digestRun, occ occurrence) {
+	start, end := occ.Period.dates()
+	slog.Info("triggered", "tenant", run.tenant.ID, "group", occ.Group, "zone", occ.Zone, "ctype", occ.cType(), "local_time", occ.Schedule.localTime(),
+		"period_start", start, "period_end", end, "outcome", "triggered")
+	run.trigger(ctx, occ)
+}
+
+func zonePayload(tn *tenant, occ occurrence) map[string]interface{} {
+	payload := map[string]interface{}{
+		"zone":       occ.Zone,
+		"type":       string(occ.cType()),
+		"local_time": occ.Schedule.localTime(),
+	}
+	if !occ.DeferredFrom.IsZero() {
+		payload["deferred_from"] = occ.DeferredFrom.Format(time.RFC3339)
+	}
+	if !occ.ShiftedFrom.IsZero() {
+		payload["shifted_from"] = occ.ShiftedFrom.Format(time.DateOnly)
+	}
+	occ.Period.payload(payload)
+	labels := tn.labels(occ)
+	payload["locale"] = labels["locale"]
+	if len(labels) > 1 {
+		delete(labels, "locale")
+		payload["labels"] = labels
+	}
+	return payload
+}
+
+// post makes a single delivery attempt to the tenant's endpoint and returns
+// the response payload so batch callers can inspect per-zone results. Retries
+// live in sender. With OAuth2 configured a 401 refreshes the access token and
+// tries once more.
+func post(ctx context.Context, tn *tenant, payload []byte) ([]byte, error) {
+	ctx, span := tracer.Start(ctx, "digest.post", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("url.full", tn.Endpoint), attribute.String("digest.tenant", tn.ID)))
+	payload, err := tn.withBodyToken(payload)
+	if err != nil {
+		endSpan(span, err)
+		return nil, err
+	}
+	respBody, status, access, err := postOnce(ctx, tn, payload)
+	if status == http.StatusUnauthorized && access != "" {
+		tn.tokens.invalidate(access)
+		respBody, status, _, err = postOnce(ctx, tn, payload)
+	}
+	if status != 0 {
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+	}
+	endSpan(span, err)
+	return respBody, err
+}
+
+func postOnce(ctx context.Context, tn *tenant, payload []byte) ([]byte, int, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tn.Endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return nil, 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	var access string
+	if tn.tokens.enabled() {
+		if access, err = tn.tokens.token(ctx); err != nil {
+			return nil, 0, "", err
+		}
+		req.Header.Set("Authorization", "Bearer "+access)
+	}
+	client, err := tn.delivery().get(ctx)
+	if err != nil {
+		return nil, 0, access, err
+	}
+	resp, err := client.Do(req)
 	//Handle Error
 	if err != nil {
-		fmt.Printf("cannot post request to the rewind server please check %v\n", err)
-		//bad practice: ignoring error here....
-		return
+		slog.Warn("cannot post request to the rewind server", "tenant", tn.ID, "target", tn.Endpoint, "error", err, "outcome", "post_error")
+		return nil, 0, access, err
 	}
 	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode >= 300 {
+		return respBody, resp.StatusCode, access, &statusError{Code: resp.StatusCode, Status: resp.Status}
+	}
+	return respBody, resp.StatusCode, access, nil
 }
 
 var timezones = map[string][]string{
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// useConfig replaces the package config, and the delivery client built
+// from it, for the rest of the test.
+func useConfig(t *testing.T, edit func(c *config)) {
+	t.Helper()
+	savedCfg, savedClient := cfg, httpClient
+	c := loadConfig()
+	c.Metrics = false
+	c.MaxAttempts = 1
+	c.RetryBackoff = 0
+	edit(&c)
+	cfg, httpClient = c, &deliveryClient{cfg: c}
+	t.Cleanup(func() { cfg, httpClient = savedCfg, savedClient })
+}
+
+// digestServer records every payload posted to it. respond picks the status
+// and body for each; nil answers 200 with an empty object.
+type digestServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	payloads []map[string]interface{}
+}
+
+func newDigestServer(t *testing.T, respond func(p map[string]interface{}) (int, string)) *digestServer {
+	t.Helper()
+	s := &digestServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var p map[string]interface{}
+		if err := json.Unmarshal(body, &p); err != nil {
+			t.Errorf("cannot decode payload: %v", err)
+		}
+		s.mu.Lock()
+		s.payloads = append(s.payloads, p)
+		s.mu.Unlock()
+		status, resp := http.StatusOK, `{}`
+		if respond != nil {
+			status, resp = respond(p)
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(resp))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// failZones answers status for single-zone payloads for one of zones.
+func failZones(status int, zones ...string) func(p map[string]interface{}) (int, string) {
+	return func(p map[string]interface{}) (int, string) {
+		for _, z := range zones {
+			if p["zone"] == z {
+				return status, `{}`
+			}
+		}
+		return http.StatusOK, `{}`
+	}
+}
+
+func (s *digestServer) zones() map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := map[string]int{}
+	for _, p := range s.payloads {
+		if z, ok := p["zone"].(string); ok {
+			out[z]++
+		}
+	}
+	return out
+}
+
+func TestRunDigestPartialFailureReturnsNil(t *testing.T) {
+	srv := newDigestServer(t, failZones(http.StatusInternalServerError, "Europe/Paris"))
+	useConfig(t, func(c *config) { c.Endpoint = srv.URL })
+	// 16:05 in UTC+1: the daily digest is due across Central Europe.
+	at := time.Date(2026, time.March, 2, 15, 5, 0, 0, time.UTC)
+	if err := runDigest(t.Context(), at); err != nil {
+		t.Fatalf("runDigest = %v, want nil after a partial failure", err)
+	}
+	zones := srv.zones()
+	if zones["Europe/Paris"] == 0 || zones["Europe/Berlin"] == 0 {
+		t.Fatalf("posted zones = %v, want Europe/Paris and Europe/Berlin", zones)
+	}
+}
+
+func TestRunDigestWithoutTenantsFails(t *testing.T) {
+	useConfig(t, func(c *config) { c.Tenants = "file:/nonexistent/tenants.json" })
+	if err := runDigest(t.Context(), time.Now().UTC()); err == nil {
+		t.Fatal("runDigest = nil, want an error when no tenant loads")
+	}
+}
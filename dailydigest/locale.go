@@ -0,0 +1,417 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// locale.tab maps each catalog country to its default locale.
+//
+//go:embed locale.tab
+var localeTab string
+
+// fallbackLocale is used for zones without a country, such as Etc/UTC, and
+// is the last step of every message lookup.
+const fallbackLocale = "en"
+
+var countryLocales = func() map[string]string {
+	m := map[string]string{}
+	for _, line := range strings.Split(localeTab, "\n") {
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		if f := strings.Split(line, "\t"); len(f) == 2 {
+			m[f[0]] = f[1]
+		}
+	}
+	return m
+}()
+
+var localeTag = regexp.MustCompile(`^[a-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+// checkLocales validates a tenant's locale overrides, which are keyed like
+// work weeks by IANA zone, abbreviation, country code or "*".
+func checkLocales(locales map[string]string) error {
+	for key, l := range locales {
+		if !localeTag.MatchString(l) {
+			return fmt.Errorf("locale for %s: %q is not a BCP 47 tag", key, l)
+		}
+	}
+	return nil
+}
+
+// localeFor returns the locale of zone, found under group: the tenant's
+// overrides first, then the default for the zone's country.
+func (tn *tenant) localeFor(group, zone string) string {
+	country := zoneCountries[zone]
+	for _, key := range []string{zone, group, country, "*"} {
+		if l, ok := tn.Locales[key]; ok && key != "" {
+			return l
+		}
+	}
+	if l, ok := countryLocales[country]; ok {
+		return l
+	}
+	return fallbackLocale
+}
+
+// messageCatalog provides the human-readable strings a trigger carries,
+// looked up by exact locale tag and key. Templates use {name} placeholders.
+type messageCatalog interface {
+	message(locale, key string) (string, bool)
+}
+
+// catalogMap is a catalog held in memory, locale to key to template.
+type catalogMap map[string]map[string]string
+
+func (c catalogMap) message(locale, key string) (string, bool) {
+	m, ok := c[locale][key]
+	return m, ok
+}
+
+// layeredCatalog answers from the first catalog that has the message.
+type layeredCatalog []messageCatalog
+
+func (l layeredCatalog) message(locale, key string) (string, bool) {
+	for _, c := range l {
+		if m, ok := c.message(locale, key); ok {
+			return m, true
+		}
+	}
+	return "", false
+}
+
+// loadMessages reads DIGEST_MESSAGES, a secret reference to a JSON object of
+// templates by locale and key, and layers it over the built-in catalog.
+func loadMessages(ctx context.Context, c config) (messageCatalog, error) {
+	if c.Messages == "" {
+		return builtinMessages, nil
+	}
+	data, err := loadSecret(ctx, c.Messages)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read messages: %w", err)
+	}
+	var custom catalogMap
+	if err := json.Unmarshal(data, &custom); err != nil {
+		return nil, fmt.Errorf("cannot decode messages: %w", err)
+	}
+	return layeredCatalog{custom, builtinMessages}, nil
+}
+
+// localizer formats labels for one locale. Lookups drop subtags from the end
+// of the tag, so "zh-Hant-TW" tries "zh-Hant-TW", "zh-Hant" and "zh", and end
+// with English.
+type localizer struct {
+	locale  string
+	catalog messageCatalog
+}
+
+func (l localizer) text(key string, args map[string]string) string {
+	tag := l.locale
+	for {
+		if m, ok := l.catalog.message(tag, key); ok {
+			return expand(m, args)
+		}
+		i := strings.LastIndexByte(tag, '-')
+		if i < 0 {
+			break
+		}
+		tag = tag[:i]
+	}
+	if tag != fallbackLocale {
+		if m, ok := l.catalog.message(fallbackLocale, key); ok {
+			return expand(m, args)
+		}
+	}
+	return ""
+}
+
+func expand(m string, args map[string]string) string {
+	pairs := make([]string, 0, 2*len(args))
+	for k, v := range args {
+		pairs = append(pairs, "{"+k+"}", v)
+	}
+	return strings.NewReplacer(pairs...).Replace(m)
+}
+
+// dateArgs are the placeholders for one date.
+func (l localizer) dateArgs(d time.Time) map[string]string {
+	return map[string]string{
+		"weekday":   l.text("weekday."+strconv.Itoa(int(d.Weekday())), nil),
+		"day":       strconv.Itoa(d.Day()),
+		"month":     l.text("month."+strconv.Itoa(int(d.Month())), nil),
+		"month_num": strconv.Itoa(int(d.Month())),
+		"year":      strconv.Itoa(d.Year()),
+	}
+}
+
+// periodLabel names the period in the locale: the date for a day, a range
+// for a week, and the month, quarter, year or fiscal period otherwise.
+func (l localizer) periodLabel(p digestPeriod) string {
+	start := p.Start
+	last := p.End.Add(-time.Nanosecond)
+	args := l.dateArgs(start)
+	switch p.Kind {
+	case periodDay:
+		return l.text("format.date", args)
+	case periodWeek:
+		args = l.dateArgs(last)
+		args["start"] = l.text("format.day_month", l.dateArgs(start))
+		args["end"] = l.text("format.day_month", args)
+		return l.text("format.range", args)
+	case periodMonth:
+		return l.text("format.month_year", args)
+	case periodQuarter:
+		args["quarter"] = strconv.Itoa((int(start.Month())-1)/3 + 1)
+		return l.text("format.quarter", args)
+	case periodYear:
+		return l.text("format.year", args)
+	case periodFiscalPeriod, periodFiscalWeek:
+		args["fiscal_year"] = p.Fiscal.Year
+		args["fiscal_period"] = strconv.Itoa(p.Fiscal.Period)
+		args["fiscal_week"] = strconv.Itoa(p.Fiscal.Week)
+		return l.text("format."+p.Kind, args)
+	}
+	return ""
+}
+
+// labels returns occ's locale with its pre-formatted labels: the local date
+// it fires on, the period it covers and a title for the digest.
+func (tn *tenant) labels(occ occurrence) map[string]interface{} {
+	locale := tn.localeFor(occ.Group, occ.Zone)
+	if occ.Period.Kind == "" {
+		return map[string]interface{}{"locale": locale}
+	}
+	catalog := tn.messages
+	if catalog == nil {
+		catalog = builtinMessages
+	}
+	l := localizer{locale: locale, catalog: catalog}
+	period := l.periodLabel(occ.Period)
+	title := l.text("title."+occ.Period.Kind, map[string]string{"period": period})
+	if title == "" {
+		title = l.text("title", map[string]string{"period": period})
+	}
+	return map[string]interface{}{
+		"locale": locale,
+		"date":   l.text("format.date", l.dateArgs(occ.Due)),
+		"period": period,
+		"title":  title,
+	}
+}
+
+// names builds the weekday and month keys of a built-in locale.
+func names(weekdays, months string) map[string]string {
+	m := map[string]string{}
+	for i, w := range strings.Split(weekdays, " ") {
+		m["weekday."+strconv.Itoa(i)] = w
+	}
+	for i, mo := range strings.Split(months, " ") {
+		m["month."+strconv.Itoa(i+1)] = mo
+	}
+	return m
+}
+
+func withMessages(base map[string]string, msgs map[string]string) map[string]string {
+	for k, v := range msgs {
+		base[k] = v
+	}
+	return base
+}
+
+// builtinMessages covers a handful of widely used languages. Anything
+// else falls back to English unless DIGEST_MESSAGES adds it.
+var builtinMessages = catalogMap{
+	"en": withMessages(names(
+		"Sunday Monday Tuesday Wednesday Thursday Friday Saturday",
+		"January February March April May June July August September October November December"),
+		map[string]string{
+			"format.date":          "{weekday}, {day} {month} {year}",
+			"format.day_month":     "{day} {month}",
+			"format.month_year":    "{month} {year}",
+			"format.range":         "{start} – {end} {year}",
+			"format.quarter":       "Q{quarter} {year}",
+			"format.year":          "{year}",
+			"format.fiscal_period": "{fiscal_year} P{fiscal_period}",
+			"format.fiscal_week":   "{fiscal_year} W{fiscal_week}",
+			"title":                "Your digest for {period}",
+			"title.week":           "Your weekly digest for {period}",
+			"title.month":          "Your monthly digest for {period}",
+			"title.quarter":        "Your quarterly digest for {period}",
+			"title.year":           "Your yearly digest for {period}",
+		}),
+	"en-US": {
+		"format.date":      "{weekday}, {month} {day}, {year}",
+		"format.day_month": "{month} {day}",
+		"format.range":     "{start} – {end}, {year}",
+	},
+	"de": withMessages(names(
+		"Sonntag Montag Dienstag Mittwoch Donnerstag Freitag Samstag",
+		"Januar Februar März April Mai Juni Juli August September Oktober November Dezember"),
+		map[string]string{
+			"format.date":       "{weekday}, {day}. {month} {year}",
+			"format.day_month":  "{day}. {month}",
+			"format.month_year": "{month} {year}",
+			"format.range":      "{start} – {end} {year}",
+			"format.quarter":    "Q{quarter} {year}",
+			"format.year":       "{year}",
+			"title":             "Ihr Digest für {period}",
+			"title.week":        "Ihr Wochen-Digest für {period}",
+			"title.month":       "Ihr Monats-Digest für {period}",
+			"title.quarter":     "Ihr Quartals-Digest für {period}",
+			"title.year":        "Ihr Jahres-Digest für {period}",
+		}),
+	"fr": withMessages(names(
+		"dimanche lundi mardi mercredi jeudi vendredi samedi",
+		"janvier février mars avril mai juin juillet août septembre octobre novembre décembre"),
+		map[string]string{
+			"format.date":       "{weekday} {day} {month} {year}",
+			"format.day_month":  "{day} {month}",
+			"format.month_year": "{month} {year}",
+			"format.range":      "du {start} au {end} {year}",
+			"format.quarter":    "T{quarter} {year}",
+			"format.year":       "{year}",
+			"title":             "Votre résumé du {period}",
+			"title.week":        "Votre résumé hebdomadaire {period}",
+			"title.month":       "Votre résumé mensuel de {period}",
+			"title.quarter":     "Votre résumé trimestriel du {period}",
+			"title.year":        "Votre résumé annuel {period}",
+		}),
+	"es": withMessages(names(
+		"domingo lunes martes miércoles jueves viernes sábado",
+		"enero febrero marzo abril mayo junio julio agosto septiembre octubre noviembre diciembre"),
+		map[string]string{
+			"format.date":       "{weekday}, {day} de {month} de {year}",
+			"format.day_month":  "{day} de {month}",
+			"format.month_year": "{month} de {year}",
+			"format.range":      "del {start} al {end} de {year}",
+			"format.quarter":    "T{quarter} {year}",
+			"format.year":       "{year}",
+			"title":             "Tu resumen del {period}",
+			"title.week":        "Tu resumen semanal {period}",
+			"title.month":       "Tu resumen mensual de {period}",
+			"title.quarter":     "Tu resumen trimestral del {period}",
+			"title.year":        "Tu resumen anual de {period}",
+		}),
+	"it": withMessages(names(
+		"domenica lunedì martedì mercoledì giovedì venerdì sabato",
+		"gennaio febbraio marzo aprile maggio giugno luglio agosto settembre ottobre novembre dicembre"),
+		map[string]string{
+			"format.date":       "{weekday} {day} {month} {year}",
+			"format.day_month":  "{day} {month}",
+			"format.month_year": "{month} {year}",
+			"format.range":      "dal {start} al {end} {year}",
+			"format.quarter":    "T{quarter} {year}",
+			"format.year":       "{year}",
+			"title":             "Il tuo riepilogo di {period}",
+			"title.week":        "Il tuo riepilogo settimanale {period}",
+			"title.month":       "Il tuo riepilogo mensile di {period}",
+			"title.quarter":     "Il tuo riepilogo trimestrale del {period}",
+			"title.year":        "Il tuo riepilogo annuale del {period}",
+		}),
+	"pt": withMessages(names(
+		"domingo segunda-feira terça-feira quarta-feira quinta-feira sexta-feira sábado",
+		"janeiro fevereiro março abril maio junho julho agosto setembro outubro novembro dezembro"),
+		map[string]string{
+			"format.date":       "{weekday}, {day} de {month} de {year}",
+			"format.day_month":  "{day} de {month}",
+			"format.month_year": "{month} de {year}",
+			"format.range":      "de {start} a {end} de {year}",
+			"format.quarter":    "T{quarter} {year}",
+			"format.year":       "{year}",
+			"title":             "Seu resumo de {period}",
+			"title.week":        "Seu resumo semanal {period}",
+			"title.month":       "Seu resumo mensal de {period}",
+			"title.quarter":     "Seu resumo trimestral do {period}",
+			"title.year":        "Seu resumo anual de {period}",
+		}),
+	"nl": withMessages(names(
+		"zondag maandag dinsdag woensdag donderdag vrijdag zaterdag",
+		"januari februari maart april mei juni juli augustus september oktober november december"),
+		map[string]string{
+			"format.date":       "{weekday} {day} {month} {year}",
+			"format.day_month":  "{day} {month}",
+			"format.month_year": "{month} {year}",
+			"format.range":      "{start} – {end} {year}",
+			"format.quarter":    "K{quarter} {year}",
+			"format.year":       "{year}",
+			"title":             "Je digest voor {period}",
+			"title.week":        "Je weekdigest voor {period}",
+			"title.month":       "Je maanddigest voor {period}",
+			"title.quarter":     "Je kwartaaldigest voor {period}",
+			"title.year":        "Je jaardigest voor {period}",
+		}),
+	"ja": withMessages(names(
+		"日曜日 月曜日 火曜日 水曜日 木曜日 金曜日 土曜日",
+		"1月 2月 3月 4月 5月 6月 7月 8月 9月 10月 11月 12月"),
+		map[string]string{
+			"format.date":       "{year}年{month_num}月{day}日{weekday}",
+			"format.day_month":  "{month_num}月{day}日",
+			"format.month_year": "{year}年{month_num}月",
+			"format.range":      "{year}年{start}～{end}",
+			"format.quarter":    "{year}年第{quarter}四半期",
+			"format.year":       "{year}年",
+			"title":             "{period}のダイジェスト",
+			"title.week":        "{period}の週間ダイジェスト",
+			"title.month":       "{period}の月間ダイジェスト",
+			"title.quarter":     "{period}のダイジェスト",
+			"title.year":        "{period}の年間ダイジェスト",
+		}),
+	"zh": withMessages(names(
+		"星期日 星期一 星期二 星期三 星期四 星期五 星期六",
+		"一月 二月 三月 四月 五月 六月 七月 八月 九月 十月 十一月 十二月"),
+		map[string]string{
+			"format.date":       "{year}年{month_num}月{day}日{weekday}",
+			"format.day_month":  "{month_num}月{day}日",
+			"format.month_year": "{year}年{month_num}月",
+			"format.range":      "{year}年{start}至{end}",
+			"format.quarter":    "{year}年第{quarter}季度",
+			"format.year":       "{year}年",
+			"title":             "{period}摘要",
+			"title.week":        "{period}每周摘要",
+			"title.month":       "{period}月度摘要",
+			"title.quarter":     "{period}摘要",
+			"title.year":        "{period}年度摘要",
+		}),
+	"zh-Hant": {
+		"title.week": "{period}每週摘要",
+	},
+	"hi": withMessages(names(
+		"रविवार सोमवार मंगलवार बुधवार गुरुवार शुक्रवार शनिवार",
+		"जनवरी फ़रवरी मार्च अप्रैल मई जून जुलाई अगस्त सितंबर अक्तूबर नवंबर दिसंबर"),
+		map[string]string{
+			"format.date":       "{weekday}, {day} {month} {year}",
+			"format.day_month":  "{day} {month}",
+			"format.month_year": "{month} {year}",
+			"format.range":      "{start} – {end} {year}",
+			"format.quarter":    "ति{quarter} {year}",
+			"format.year":       "{year}",
+			"title":             "{period} का डाइजेस्ट",
+			"title.week":        "{period} का साप्ताहिक डाइजेस्ट",
+			"title.month":       "{period} का मासिक डाइजेस्ट",
+			"title.quarter":     "{period} का तिमाही डाइजेस्ट",
+			"title.year":        "{period} का वार्षिक डाइजेस्ट",
+		}),
+	"ar": withMessages(names(
+		"الأحد الاثنين الثلاثاء الأربعاء الخميس الجمعة السبت",
+		"يناير فبراير مارس أبريل مايو يونيو يوليو أغسطس سبتمبر أكتوبر نوفمبر ديسمبر"),
+		map[string]string{
+			"format.date":       "{weekday}، {day} {month} {year}",
+			"format.day_month":  "{day} {month}",
+			"format.month_year": "{month} {year}",
+			"format.range":      "{start} – {end} {year}",
+			"format.quarter":    "الربع {quarter} {year}",
+			"format.year":       "{year}",
+			"title":             "ملخصك لـ {period}",
+			"title.week":        "ملخصك الأسبوعي لـ {period}",
+			"title.month":       "ملخصك الشهري لـ {period}",
+			"title.quarter":     "ملخصك الفصلي لـ {period}",
+			"title.year":        "ملخصك السنوي لـ {period}",
+		}),
+}
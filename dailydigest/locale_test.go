@@ -0,0 +1,132 @@
+package main
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestLocaleFor(t *testing.T) {
+	tn := &tenant{Locales: map[string]string{
+		"Asia/Kolkata": "en-IN",
+		"CET":          "de",
+		"FR":           "fr-CA",
+	}}
+	wildcard := &tenant{Locales: map[string]string{"*": "es", "JP": "ja"}}
+	for _, tt := range []struct {
+		tn          *tenant
+		group, zone string
+		want        string
+	}{
+		{&tenant{}, "GMT", "Europe/London", "en-GB"},
+		{&tenant{}, "CET", "Europe/Paris", "fr-FR"},
+		{&tenant{}, "CST", "Asia/Taipei", "zh-Hant-TW"},
+		{&tenant{}, "IST", "Asia/Kolkata", "hi-IN"},
+		{&tenant{}, "AST", "Asia/Riyadh", "ar-SA"},
+		{&tenant{}, "UTC", "Etc/UTC", fallbackLocale},
+		{tn, "IST", "Asia/Kolkata", "en-IN"},
+		{tn, "CET", "Europe/Paris", "de"},
+		{tn, "CEST", "Europe/Paris", "fr-CA"},
+		{tn, "CET", "Europe/Rome", "de"},
+		{tn, "GMT", "Europe/London", "en-GB"},
+		{wildcard, "JST", "Asia/Tokyo", "ja"},
+		{wildcard, "GMT", "Europe/London", "es"},
+		{wildcard, "UTC", "Etc/UTC", "es"},
+	} {
+		if got := tt.tn.localeFor(tt.group, tt.zone); got != tt.want {
+			t.Errorf("localeFor(%s, %s) with %v = %s, want %s", tt.group, tt.zone, tt.tn.Locales, got, tt.want)
+		}
+	}
+}
+
+func TestLocalizerFallback(t *testing.T) {
+	custom := catalogMap{
+		"fr-CA": {"title.week": "Votre sommaire de la semaine {period}"},
+		"sw":    {"weekday.1": "Jumatatu"},
+	}
+	catalog := layeredCatalog{custom, builtinMessages}
+	for _, tt := range []struct {
+		locale, key, want string
+	}{
+		{"zh-Hant-TW", "title.week", "{period}每週摘要"},
+		{"zh-Hant-TW", "title.month", "{period}月度摘要"},
+		{"zh-Hans-SG", "title.week", "{period}每周摘要"},
+		{"fr-CA", "title.week", "Votre sommaire de la semaine {period}"},
+		{"fr-CA", "title.month", "Votre résumé mensuel de {period}"},
+		{"en-US", "format.date", "{weekday}, {month} {day}, {year}"},
+		{"en-GB", "format.date", "{weekday}, {day} {month} {year}"},
+		{"sw-KE", "weekday.1", "Jumatatu"},
+		{"sw-KE", "weekday.2", "Tuesday"},
+		{"ko-KR", "title", "Your digest for {period}"},
+		{"de-AT", "format.fiscal_week", "{fiscal_year} W{fiscal_week}"},
+		{"de", "no.such.key", ""},
+	} {
+		l := localizer{locale: tt.locale, catalog: catalog}
+		if got := l.text(tt.key, nil); got != tt.want {
+			t.Errorf("text(%s, %s) = %q, want %q", tt.locale, tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestBuiltinMessagesComplete(t *testing.T) {
+	required := []string{"format.date", "format.day_month", "format.month_year", "format.range", "format.quarter", "format.year", "title"}
+	for locale, msgs := range builtinMessages {
+		if _, ok := msgs["weekday.0"]; !ok {
+			continue // a regional variant layered over its language
+		}
+		for i := 0; i < 7; i++ {
+			if msgs["weekday."+strconv.Itoa(i)] == "" {
+				t.Errorf("%s has no weekday.%d", locale, i)
+			}
+		}
+		for i := 1; i <= 12; i++ {
+			if msgs["month."+strconv.Itoa(i)] == "" {
+				t.Errorf("%s has no month.%d", locale, i)
+			}
+		}
+		for _, key := range required {
+			if msgs[key] == "" {
+				t.Errorf("%s has no %s", locale, key)
+			}
+		}
+	}
+}
+
+func TestLabels(t *testing.T) {
+	at := time.Date(2026, time.March, 2, 10, 35, 0, 0, time.UTC)
+	for _, tt := range []struct {
+		group, zone string
+		locale      string
+		date, title string
+	}{
+		{"GMT", "Europe/London", "en-GB", "Monday, 2 March 2026", "Your digest for Monday, 2 March 2026"},
+		{"EST", "America/New_York", "en-US", "Monday, March 2, 2026", "Your digest for Monday, March 2, 2026"},
+		{"CET", "Europe/Berlin", "de-DE", "Montag, 2. März 2026", "Ihr Digest für Montag, 2. März 2026"},
+		{"IST", "Asia/Kolkata", "hi-IN", "सोमवार, 2 मार्च 2026", "सोमवार, 2 मार्च 2026 का डाइजेस्ट"},
+		{"AST", "Asia/Riyadh", "ar-SA", "الاثنين، 2 مارس 2026", "ملخصك لـ الاثنين، 2 مارس 2026"},
+		{"KST", "Asia/Seoul", "ko-KR", "Monday, 2 March 2026", "Your digest for Monday, 2 March 2026"},
+	} {
+		loc, err := loadLocation(tt.zone)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tn := &tenant{}
+		occ := occurrence{Group: tt.group, Zone: tt.zone, Schedule: dailySchedule, Due: at.In(loc)}
+		occ.Period = tn.periodFor(occ)
+		got := tn.labels(occ)
+		if got["locale"] != tt.locale || got["date"] != tt.date || got["period"] != tt.date || got["title"] != tt.title {
+			t.Errorf("%s labels = %v, want %s %q %q", tt.zone, got, tt.locale, tt.date, tt.title)
+		}
+	}
+}
+
+func TestCheckLocales(t *testing.T) {
+	if err := checkLocales(map[string]string{"IN": "en-IN", "*": "zh-Hant-TW", "FR": "fr"}); err != nil {
+		t.Error(err)
+	}
+	for _, bad := range []string{"", "english", "en_GB", "EN"} {
+		if err := checkLocales(map[string]string{"GB": bad}); err == nil {
+			t.Errorf("locale %q accepted", bad)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+)
+
+// baseLogger writes JSON lines to stdout, which Lambda forwards to
+// CloudWatch Logs as-is so Logs Insights can query every field.
+var baseLogger = slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+	Level: parseLevel(cfg.LogLevel),
+}))
+
+func parseLevel(s string) slog.Level {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(strings.ToUpper(s))); err != nil {
+		return slog.LevelInfo
+	}
+	return l
+}
+
+type runIDKey struct{}
+
+// startRun installs the default logger for one invocation and returns ctx
+// carrying a fresh run ID. A Lambda instance handles one invocation at a
+// time, so every slog call made until the next invocation carries this
+// request and run ID.
+func startRun(ctx context.Context) (context.Context, string) {
+	runID := newRunID()
+	requestID := ""
+	if lc, ok := lambdacontext.FromContext(ctx); ok {
+		requestID = lc.AwsRequestID
+	}
+	slog.SetDefault(baseLogger.With("request_id", requestID, "run_id", runID))
+	return context.WithValue(ctx, runIDKey{}, runID), runID
+}
+
+// runIDFrom returns the run ID installed by startRun.
+func runIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(runIDKey{}).(string)
+	return id
+}
+
+func newRunID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"sort"
+	"time"
+)
+
+// emfMaxValues is the most values CloudWatch accepts for one metric in one
+// Embedded Metric Format document.
+const emfMaxValues = 100
+
+type countKey struct {
+	CType   CType
+	Group   string
+	Outcome string
+}
+
+type deliveryKey struct {
+	CType   CType
+	Outcome string
+}
+
+// runMetrics aggregates one invocation's outcomes and writes them as
+// CloudWatch Embedded Metric Format documents, which CloudWatch Logs turns
+// into metrics without any PutMetricData calls.
+type runMetrics struct {
+	namespace string
+	counts    map[countKey]int
+	latency   map[deliveryKey][]float64
+	retries   map[deliveryKey][]float64
+}
+
+func newRunMetrics(c config) *runMetrics {
+	if !c.Metrics {
+		return nil
+	}
+	return &runMetrics{
+		namespace: c.MetricsNamespace,
+		counts:    map[countKey]int{},
+		latency:   map[deliveryKey][]float64{},
+		retries:   map[deliveryKey][]float64{},
+	}
+}
+
+// count adds one digest with the given outcome for an abbreviation group.
+func (m *runMetrics) count(cType CType, group, outcome string) {
+	if m == nil {
+		return
+	}
+	if cType == "" {
+		cType = "none"
+	}
+	m.counts[countKey{cType, group, outcome}]++
+}
+
+// delivery records how long one request took, retries included, and how
+// many retries it needed.
+func (m *runMetrics) delivery(cType CType, outcome string, latency time.Duration, attempts int) {
+	if m == nil {
+		return
+	}
+	k := deliveryKey{cType, outcome}
+	m.latency[k] = append(m.latency[k], float64(latency.Milliseconds()))
+	retries := attempts - 1
+	if retries < 0 {
+		retries = 0
+	}
+	m.retries[k] = append(m.retries[k], float64(retries))
+}
+
+// emit writes every aggregated metric to w, followed by the evaluation
+// duration of the invocation.
+func (m *runMetrics) emit(w io.Writer, evaluation time.Duration) {
+	if m == nil {
+		return
+	}
+	now := time.Now()
+	enc := json.NewEncoder(w)
+	write := func(dims []string, values map[string]interface{}, metrics ...emfMetric) {
+		doc := map[string]interface{}{
+			"_aws": map[string]interface{}{
+				"Timestamp": now.UnixMilli(),
+				"CloudWatchMetrics": []map[string]interface{}{{
+					"Namespace":  m.namespace,
+					"Dimensions": [][]string{dims},
+					"Metrics":    metrics,
+				}},
+			},
+		}
+		for k, v := range values {
+			doc[k] = v
+		}
+		if err := enc.Encode(doc); err != nil {
+			slog.Warn("cannot write metrics", "error", err)
+		}
+	}
+
+	countKeys := make([]countKey, 0, len(m.counts))
+	for k := range m.counts {
+		countKeys = append(countKeys, k)
+	}
+	sort.Slice(countKeys, func(i, j int) bool {
+		a, b := countKeys[i], countKeys[j]
+		if a.CType != b.CType {
+			return a.CType < b.CType
+		}
+		if a.Group != b.Group {
+			return a.Group < b.Group
+		}
+		return a.Outcome < b.Outcome
+	})
+	for _, k := range countKeys {
+		write([]string{"CType", "Group", "Outcome"}, map[string]interface{}{
+			"CType": k.CType, "Group": k.Group, "Outcome": k.Outcome, "Digests": m.counts[k],
+		}, emfMetric{"Digests", "Count"})
+	}
+
+	for k, latencies := range m.latency {
+		retries := m.retries[k]
+		for len(latencies) > 0 {
+			n := len(latencies)
+			if n > emfMaxValues {
+				n = emfMaxValues
+			}
+			write([]string{"CType", "Outcome"}, map[string]interface{}{
+				"CType": k.CType, "Outcome": k.Outcome,
+				"DeliveryLatency": latencies[:n], "DeliveryRetries": retries[:n],
+			}, emfMetric{"DeliveryLatency", "Milliseconds"}, emfMetric{"DeliveryRetries", "Count"})
+			latencies, retries = latencies[n:], retries[n:]
+		}
+	}
+
+	write([]string{}, map[string]interface{}{
+		"EvaluationDuration": float64(evaluation.Milliseconds()),
+	}, emfMetric{"EvaluationDuration", "Milliseconds"})
+}
+
+type emfMetric struct {
+	Name string `json:"Name"`
+	Unit string `json:"Unit"`
+}
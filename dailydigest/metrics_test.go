@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type emfDoc struct {
+	AWS struct {
+		Timestamp         int64
+		CloudWatchMetrics []struct {
+			Namespace  string
+			Dimensions [][]string
+			Metrics    []emfMetric
+		}
+	} `json:"_aws"`
+	CType              CType
+	Group              string
+	Outcome            string
+	Digests            int
+	DeliveryLatency    []float64
+	DeliveryRetries    []float64
+	EvaluationDuration *float64
+}
+
+func emitted(t *testing.T, m *runMetrics, evaluation time.Duration) []emfDoc {
+	t.Helper()
+	var buf bytes.Buffer
+	m.emit(&buf, evaluation)
+	var docs []emfDoc
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var d emfDoc
+		if err := dec.Decode(&d); err != nil {
+			t.Fatal(err)
+		}
+		docs = append(docs, d)
+	}
+	return docs
+}
+
+func TestMetricsDisabled(t *testing.T) {
+	m := newRunMetrics(config{Metrics: false})
+	if m != nil {
+		t.Fatal("metrics built while disabled")
+	}
+	m.count(TypeDailyAt4P, "CET", "triggered")
+	m.delivery(TypeDailyAt4P, "delivered", time.Second, 1)
+	if docs := emitted(t, m, time.Second); len(docs) != 0 {
+		t.Errorf("disabled metrics wrote %d documents", len(docs))
+	}
+	if loadConfig().Metrics {
+		t.Error("metrics are on by default")
+	}
+}
+
+func TestMetricsDocuments(t *testing.T) {
+	m := newRunMetrics(config{Metrics: true, MetricsNamespace: "Digest/Test"})
+	m.count(TypeDailyAt4P, "CET", "triggered")
+	m.count(TypeDailyAt4P, "CET", "triggered")
+	m.count(TypeDailyAt4P, "CET", "failed")
+	m.count("", "CET", "skipped")
+	m.delivery(TypeDailyAt4P, "delivered", 120*time.Millisecond, 1)
+	m.delivery(TypeDailyAt4P, "delivered", 900*time.Millisecond, 3)
+	m.delivery(TypeDailyAt4P, "failed", time.Second, 0)
+
+	docs := emitted(t, m, 1500*time.Millisecond)
+	if len(docs) != 6 {
+		t.Fatalf("got %d documents, want 3 counts, 2 deliveries and the duration", len(docs))
+	}
+	for _, d := range docs {
+		if len(d.AWS.CloudWatchMetrics) != 1 || d.AWS.CloudWatchMetrics[0].Namespace != "Digest/Test" || d.AWS.Timestamp == 0 {
+			t.Errorf("document header = %+v", d.AWS)
+		}
+	}
+
+	type count struct {
+		CType          CType
+		Group, Outcome string
+		Digests        int
+	}
+	var counts []count
+	for _, d := range docs[:3] {
+		cw := d.AWS.CloudWatchMetrics[0]
+		if !reflect.DeepEqual(cw.Dimensions, [][]string{{"CType", "Group", "Outcome"}}) || !reflect.DeepEqual(cw.Metrics, []emfMetric{{"Digests", "Count"}}) {
+			t.Errorf("count document declares %v %v", cw.Dimensions, cw.Metrics)
+		}
+		counts = append(counts, count{d.CType, d.Group, d.Outcome, d.Digests})
+	}
+	want := []count{
+		{TypeDailyAt4P, "CET", "failed", 1},
+		{TypeDailyAt4P, "CET", "triggered", 2},
+		{"none", "CET", "skipped", 1},
+	}
+	if !reflect.DeepEqual(counts, want) {
+		t.Errorf("counts = %v, want %v", counts, want)
+	}
+
+	deliveries := map[string]emfDoc{}
+	for _, d := range docs[3:5] {
+		cw := d.AWS.CloudWatchMetrics[0]
+		if !reflect.DeepEqual(cw.Dimensions, [][]string{{"CType", "Outcome"}}) ||
+			!reflect.DeepEqual(cw.Metrics, []emfMetric{{"DeliveryLatency", "Milliseconds"}, {"DeliveryRetries", "Count"}}) {
+			t.Errorf("delivery document declares %v %v", cw.Dimensions, cw.Metrics)
+		}
+		deliveries[d.Outcome] = d
+	}
+	if d := deliveries["delivered"]; !reflect.DeepEqual(d.DeliveryLatency, []float64{120, 900}) || !reflect.DeepEqual(d.DeliveryRetries, []float64{0, 2}) {
+		t.Errorf("delivered latency %v retries %v", d.DeliveryLatency, d.DeliveryRetries)
+	}
+	if d := deliveries["failed"]; !reflect.DeepEqual(d.DeliveryLatency, []float64{1000}) || !reflect.DeepEqual(d.DeliveryRetries, []float64{0}) {
+		t.Errorf("failed latency %v retries %v", d.DeliveryLatency, d.DeliveryRetries)
+	}
+
+	last := docs[5]
+	if cw := last.AWS.CloudWatchMetrics[0]; len(cw.Dimensions) != 1 || len(cw.Dimensions[0]) != 0 {
+		t.Errorf("duration document dimensions = %v, want one empty set", cw.Dimensions)
+	}
+	if last.EvaluationDuration == nil || *last.EvaluationDuration != 1500 {
+		t.Errorf("evaluation duration = %v, want 1500", last.EvaluationDuration)
+	}
+}
+
+func TestMetricsSplitLargeDeliveries(t *testing.T) {
+	m := newRunMetrics(config{Metrics: true, MetricsNamespace: "DailyDigest"})
+	for i := 0; i < 2*emfMaxValues+1; i++ {
+		m.delivery(TypeWeeklyAt9A, "delivered", time.Duration(i)*time.Millisecond, 1)
+	}
+	var sizes []int
+	for _, d := range emitted(t, m, 0) {
+		if d.DeliveryLatency != nil {
+			sizes = append(sizes, len(d.DeliveryLatency))
+			if len(d.DeliveryRetries) != len(d.DeliveryLatency) {
+				t.Errorf("%d retries for %d latencies", len(d.DeliveryRetries), len(d.DeliveryLatency))
+			}
+		}
+	}
+	if !reflect.DeepEqual(sizes, []int{emfMaxValues, emfMaxValues, 1}) {
+		t.Errorf("delivery documents hold %v values, want at most %d each", sizes, emfMaxValues)
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// oauthSettings configures the OAuth2 client-credentials grant for one
+// endpoint. ClientSecret is a secret reference (see loadSecret).
+type oauthSettings struct {
+	TokenURL     string `json:"token_url"`
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	Scope        string `json:"scope,omitempty"`
+	Audience     string `json:"audience,omitempty"`
+}
+
+// tokenSource fetches access tokens with the OAuth2 client-credentials grant
+// and caches them across warm invocations. A token is replaced
+// refreshBefore its expiry so a request never goes out with one that is
+// about to lapse; for short-lived tokens the margin is capped at half the
+// lifetime so each token is still reused. A nil *tokenSource means OAuth2 is
+// not in use.
+type tokenSource struct {
+	settings      oauthSettings
+	refreshBefore time.Duration
+	client        *http.Client
+
+	mu       sync.Mutex
+	access   string
+	expires  time.Time
+	lifetime time.Duration
+}
+
+var (
+	tokenSourcesMu sync.Mutex
+	tokenSources   = map[oauthSettings]*tokenSource{}
+)
+
+// tokenSourceFor returns the cached token source for settings, so tokens
+// survive warm invocations even though tenants are reloaded every run. It
+// returns nil when settings has no token URL.
+func tokenSourceFor(c config, settings oauthSettings) *tokenSource {
+	if settings.TokenURL == "" {
+		return nil
+	}
+	tokenSourcesMu.Lock()
+	defer tokenSourcesMu.Unlock()
+	s, ok := tokenSources[settings]
+	if !ok {
+		s = &tokenSource{settings: settings, client: &http.Client{Timeout: c.Timeout}}
+		tokenSources[settings] = s
+	}
+	s.refreshBefore = c.OAuthRefreshBefore
+	return s
+}
+
+// enabled reports whether outgoing requests should carry a bearer token.
+func (s *tokenSource) enabled() bool {
+	return s != nil
+}
+
+// token returns a cached access token, fetching a new one when needed.
+func (s *tokenSource) token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.access != "" && time.Until(s.expires) > min(s.refreshBefore, s.lifetime/2) {
+		return s.access, nil
+	}
+	return s.fetch(ctx)
+}
+
+// invalidate drops access if it is still the cached token, so the next call
+// to token fetches a fresh one. Used when the endpoint answers 401.
+func (s *tokenSource) invalidate(access string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.access == access {
+		s.access = ""
+	}
+}
+
+func (s *tokenSource) fetch(ctx context.Context) (string, error) {
+	secret, err := loadSecret(ctx, s.settings.ClientSecret)
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if s.settings.Scope != "" {
+		form.Set("scope", s.settings.Scope)
+	}
+	if s.settings.Audience != "" {
+		form.Set("audience", s.settings.Audience)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.settings.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(s.settings.ClientID), url.QueryEscape(strings.TrimSpace(string(secret))))
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("cannot reach token endpoint: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token endpoint returned %s: %s", resp.Status, body)
+	}
+	var tok struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &tok); err != nil {
+		return "", fmt.Errorf("cannot decode token response: %w", err)
+	}
+	if tok.AccessToken == "" {
+		return "", fmt.Errorf("token endpoint returned no access_token")
+	}
+	if tok.TokenType != "" && !strings.EqualFold(tok.TokenType, "bearer") {
+		return "", fmt.Errorf("unsupported token type %q", tok.TokenType)
+	}
+	s.access = tok.AccessToken
+	s.lifetime = time.Duration(tok.ExpiresIn) * time.Second
+	if tok.ExpiresIn == 0 {
+		// no lifetime given: reuse it until the endpoint rejects it.
+		s.lifetime = 24 * time.Hour
+	}
+	s.expires = time.Now().Add(s.lifetime)
+	return s.access, nil
+}
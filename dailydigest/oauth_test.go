@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// tokenServer issues numbered tokens that live expiresIn seconds, or answers
+// status when it is not 200.
+func tokenServer(t *testing.T, expiresIn int, status int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		if id, secret, ok := r.BasicAuth(); !ok || id != "client" || secret != "s3cret" {
+			t.Errorf("basic auth = %q, %q, %v", id, secret, ok)
+		}
+		if err := r.ParseForm(); err != nil || r.Form.Get("grant_type") != "client_credentials" {
+			t.Errorf("grant_type = %q, %v", r.Form.Get("grant_type"), err)
+		}
+		if status != http.StatusOK {
+			http.Error(w, `{"error":"invalid_client"}`, status)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"tok-%d","token_type":"Bearer","expires_in":%d}`, n, expiresIn)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func newTestTokenSource(url string, refreshBefore time.Duration) *tokenSource {
+	return &tokenSource{
+		settings:      oauthSettings{TokenURL: url, ClientID: "client", ClientSecret: "s3cret"},
+		refreshBefore: refreshBefore,
+		client:        http.DefaultClient,
+	}
+}
+
+func TestTokenSourceCaches(t *testing.T) {
+	srv, calls := tokenServer(t, 3600, http.StatusOK)
+	s := newTestTokenSource(srv.URL, time.Minute)
+	for i := 0; i < 3; i++ {
+		tok, err := s.token(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tok != "tok-1" {
+			t.Fatalf("call %d: token = %q, want tok-1", i, tok)
+		}
+	}
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("token endpoint called %d times, want 1", n)
+	}
+}
+
+func TestTokenSourceRefreshesNearExpiry(t *testing.T) {
+	srv, calls := tokenServer(t, 3600, http.StatusOK)
+	s := newTestTokenSource(srv.URL, time.Minute)
+	if _, err := s.token(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// inside the refresh margin
+	s.expires = time.Now().Add(30 * time.Second)
+	tok, err := s.token(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok != "tok-2" || calls.Load() != 2 {
+		t.Fatalf("token = %q after %d calls, want tok-2 after 2", tok, calls.Load())
+	}
+}
+
+func TestTokenSourceShortLifetime(t *testing.T) {
+	// expires_in below the refresh margin must not fetch on every call.
+	srv, calls := tokenServer(t, 30, http.StatusOK)
+	s := newTestTokenSource(srv.URL, time.Minute)
+	for i := 0; i < 3; i++ {
+		if _, err := s.token(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("token endpoint called %d times, want 1", n)
+	}
+}
+
+func TestTokenSourceInvalidate(t *testing.T) {
+	srv, _ := tokenServer(t, 3600, http.StatusOK)
+	s := newTestTokenSource(srv.URL, time.Minute)
+	first, _ := s.token(context.Background())
+	s.invalidate("stale")
+	if tok, _ := s.token(context.Background()); tok != first {
+		t.Fatalf("invalidating another token replaced %q with %q", first, tok)
+	}
+	s.invalidate(first)
+	if tok, _ := s.token(context.Background()); tok == first {
+		t.Fatalf("token %q still cached after invalidate", tok)
+	}
+}
+
+func TestTokenSourceErrorResponse(t *testing.T) {
+	srv, calls := tokenServer(t, 3600, http.StatusUnauthorized)
+	s := newTestTokenSource(srv.URL, time.Minute)
+	for i := 0; i < 2; i++ {
+		_, err := s.token(context.Background())
+		if err == nil || !strings.Contains(err.Error(), "401") {
+			t.Fatalf("err = %v, want a 401 error", err)
+		}
+	}
+	if n := calls.Load(); n != 2 {
+		t.Fatalf("token endpoint called %d times, want 2: errors must not be cached", n)
+	}
+}
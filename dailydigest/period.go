@@ -0,0 +1,299 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	periodDay     = "day"
+	periodWeek    = "week"
+	periodMonth   = "month"
+	periodQuarter = "quarter"
+	periodYear    = "year"
+)
+
+const (
+	anchorFirstDay         = "first_day"
+	anchorLastDay          = "last_day"
+	anchorNthWeekday       = "nth_weekday"
+	anchorFirstBusinessDay = "first_business_day"
+	anchorLastBusinessDay  = "last_business_day"
+)
+
+// periodAnchor picks the day in each period on which a calendar-period
+// digest fires. For nth_weekday, N counts from the start of the period, or
+// from its end when negative. Covers is "previous" or "current"; by default a
+// digest anchored near the start of a period covers the one before it, and
+// one anchored at the end covers the period it ends.
+type periodAnchor struct {
+	Kind    string `json:"kind"`
+	N       int    `json:"n,omitempty"`
+	Weekday string `json:"weekday,omitempty"`
+	Covers  string `json:"covers,omitempty"`
+}
+
+var (
+	monthlySchedule   = schedule{CType: TypeMonthlyAt9A, Hour: 9, Period: periodMonth, Anchor: &periodAnchor{Kind: anchorFirstBusinessDay}}
+	quarterlySchedule = schedule{CType: TypeQuarterlyAt9A, Hour: 9, Period: periodQuarter, Anchor: &periodAnchor{Kind: anchorFirstBusinessDay}}
+	yearlySchedule    = schedule{CType: TypeYearlyAt9A, Hour: 9, Period: periodYear, Anchor: &periodAnchor{Kind: anchorFirstBusinessDay}}
+
+	fiscalPeriodSchedule = schedule{CType: TypeFiscalPeriodAt9A, Hour: 9, Period: periodFiscalPeriod, Anchor: &periodAnchor{Kind: anchorFirstDay}}
+	fiscalWeekSchedule   = schedule{CType: TypeFiscalWeekAt9A, Hour: 9, Period: periodFiscalWeek, Anchor: &periodAnchor{Kind: anchorFirstDay}}
+)
+
+// periodTypes are the calendar-period CTypes. Unlike the daily and weekly
+// digests a tenant only gets them when it lists them in ctypes.
+var periodTypes = []CType{TypeMonthlyAt9A, TypeQuarterlyAt9A, TypeYearlyAt9A, TypeFiscalPeriodAt9A, TypeFiscalWeekAt9A}
+
+func isPeriodType(cType CType) bool {
+	for _, ct := range periodTypes {
+		if ct == cType {
+			return true
+		}
+	}
+	return false
+}
+
+// preparePeriod fills in a tenant schedule's period and anchor from the
+// built-in schedule of its CType and validates them.
+func (s *schedule) preparePeriod() error {
+	if !isPeriodType(s.CType) {
+		if s.Period != "" || s.Anchor != nil {
+			return fmt.Errorf("schedule for %s cannot have a period or anchor", s.CType)
+		}
+		return nil
+	}
+	for _, b := range builtinSchedules {
+		if b.CType != s.CType {
+			continue
+		}
+		if s.Period == "" {
+			s.Period = b.Period
+		}
+		if s.Anchor == nil {
+			s.Anchor = b.Anchor
+		}
+	}
+	switch s.Period {
+	case periodMonth, periodQuarter, periodYear, periodFiscalPeriod, periodFiscalWeek:
+	default:
+		return fmt.Errorf("schedule for %s has unknown period %q", s.CType, s.Period)
+	}
+	if s.Weekday != nil || s.FirstWorkday {
+		return fmt.Errorf("schedule for %s is anchored, it cannot set a weekday", s.CType)
+	}
+	a := s.Anchor
+	switch a.Kind {
+	case anchorFirstDay, anchorLastDay, anchorFirstBusinessDay, anchorLastBusinessDay:
+	case anchorNthWeekday:
+		if _, err := parseWeekday(a.Weekday); err != nil {
+			return fmt.Errorf("schedule for %s: %w", s.CType, err)
+		}
+		if a.N == 0 || a.N > 5 || a.N < -5 {
+			return fmt.Errorf("schedule for %s: nth_weekday needs n between -5 and 5, not 0", s.CType)
+		}
+	default:
+		return fmt.Errorf("schedule for %s has unknown anchor %q", s.CType, a.Kind)
+	}
+	switch a.Covers {
+	case "", "previous", "current":
+	default:
+		return fmt.Errorf("schedule for %s: covers must be previous or current", s.CType)
+	}
+	return nil
+}
+
+// periodBounds returns the first and last local date of the period that
+// contains day, as midnights in day's location. Fiscal periods need fc.
+func periodBounds(period string, day time.Time, fc *fiscalCalendar) (start, end time.Time) {
+	if period == periodFiscalPeriod || period == periodFiscalWeek {
+		return fc.bounds(period, day)
+	}
+	y, m, _ := day.Date()
+	months := 1
+	switch period {
+	case periodQuarter:
+		m, months = (m-1)/3*3+1, 3
+	case periodYear:
+		m, months = time.January, 12
+	}
+	start = localMidnight(y, m, 1, day.Location())
+	end = start.AddDate(0, months, -1)
+	return start, localMidnight(end.Year(), end.Month(), end.Day(), day.Location())
+}
+
+// localMidnight returns the first instant of the local date y-m-d in loc,
+// which is later than midnight where a DST change skips it.
+func localMidnight(y int, m time.Month, d int, loc *time.Location) time.Time {
+	t := time.Date(y, m, d, 0, 0, 0, 0, loc)
+	for t.Day() != time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Day() {
+		// time.Date resolves a skipped midnight to the previous day.
+		t = t.Add(15 * time.Minute).Truncate(15 * time.Minute)
+	}
+	return t
+}
+
+// addDays returns the first instant of the local date n days after t's. It
+// steps by date rather than with AddDate, which lands on the previous day
+// where it meets a skipped midnight.
+func addDays(t time.Time, n int) time.Time {
+	y, m, d := t.Date()
+	return localMidnight(y, m, d+n, t.Location())
+}
+
+// anchorDate returns the day in the period containing day on which s fires,
+// or the zero time if the anchor does not exist in that period, such as a
+// fifth Monday.
+func (s schedule) anchorDate(day time.Time, businessDay func(time.Time) bool, fc *fiscalCalendar) time.Time {
+	start, end := periodBounds(s.Period, day, fc)
+	a := s.Anchor
+	switch a.Kind {
+	case anchorFirstDay:
+		return start
+	case anchorLastDay:
+		return end
+	case anchorFirstBusinessDay:
+		for d := start; !d.After(end); d = addDays(d, 1) {
+			if businessDay(d) {
+				return d
+			}
+		}
+	case anchorLastBusinessDay:
+		for d := end; !d.Before(start); d = addDays(d, -1) {
+			if businessDay(d) {
+				return d
+			}
+		}
+	case anchorNthWeekday:
+		wd, _ := parseWeekday(a.Weekday)
+		var d time.Time
+		if a.N > 0 {
+			d = addDays(start, (int(wd)-int(start.Weekday())+7)%7+7*(a.N-1))
+		} else {
+			d = addDays(end, -((int(end.Weekday())-int(wd)+7)%7)+7*(a.N+1))
+		}
+		if !d.Before(start) && !d.After(end) {
+			return d
+		}
+	}
+	return time.Time{}
+}
+
+// coveredPeriod returns the first and last local date of the period a digest
+// that fired on day reports on.
+func (s schedule) coveredPeriod(day time.Time, fc *fiscalCalendar) (start, end time.Time) {
+	covers := s.Anchor.Covers
+	if covers == "" {
+		covers = "current"
+		switch s.Anchor.Kind {
+		case anchorFirstDay, anchorFirstBusinessDay:
+			covers = "previous"
+		case anchorNthWeekday:
+			if s.Anchor.N > 0 {
+				covers = "previous"
+			}
+		}
+	}
+	start, end = periodBounds(s.Period, day, fc)
+	if covers == "previous" {
+		start, end = periodBounds(s.Period, addDays(start, -1), fc)
+	}
+	return start, end
+}
+
+// sameDate reports whether a and b fall on the same local calendar date.
+func sameDate(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
+
+// digestPeriod is the local time a digest reports on, from Start up to but
+// not including End. Both are local midnights, or the first instant of the
+// day where midnight is skipped by a DST change. Fiscal places Start in the
+// tenant's fiscal calendar, if it has one.
+type digestPeriod struct {
+	Kind   string
+	Start  time.Time
+	End    time.Time
+	Fiscal fiscalID
+}
+
+// periodFor works out what occ covers in its zone: the local day for a daily
+// digest, the work week for a weekly one (the previous week when it fires on
+// the week's first day), and the anchored calendar period for the rest.
+func (tn *tenant) periodFor(occ occurrence) digestPeriod {
+	day := occ.Due
+	if !occ.ShiftedFrom.IsZero() {
+		day = occ.ShiftedFrom
+	}
+	if day.IsZero() {
+		return digestPeriod{}
+	}
+	midnight := func(t time.Time) time.Time {
+		return localMidnight(t.Year(), t.Month(), t.Day(), t.Location())
+	}
+	s := occ.Schedule
+	var p digestPeriod
+	switch {
+	case s.Period != "":
+		start, end := s.coveredPeriod(day, tn.Fiscal)
+		p = digestPeriod{Kind: s.Period, Start: start, End: addDays(end, 1)}
+	case s.Weekday != nil || s.FirstWorkday:
+		first := tn.workWeekFor(occ.Group, occ.Zone, day).FirstWorkday
+		back := (int(day.Weekday()) - int(first) + 7) % 7
+		if back == 0 {
+			back = 7
+		}
+		start := addDays(day, -back)
+		p = digestPeriod{Kind: periodWeek, Start: start, End: addDays(start, 7)}
+	default:
+		start := midnight(day)
+		p = digestPeriod{Kind: periodDay, Start: start, End: addDays(start, 1)}
+	}
+	if tn.Fiscal != nil {
+		p.Fiscal = tn.Fiscal.id(p.Start)
+	}
+	return p
+}
+
+// dates returns the first and last local date of the period.
+func (p digestPeriod) dates() (start, end string) {
+	if p.Kind == "" {
+		return "", ""
+	}
+	return p.Start.Format(time.DateOnly), p.End.Add(-time.Nanosecond).Format(time.DateOnly)
+}
+
+// isoWeek is the ISO 8601 week the period starts in, e.g. "2026-W10".
+func (p digestPeriod) isoWeek() string {
+	if p.Kind == "" {
+		return ""
+	}
+	y, w := p.Start.ISOWeek()
+	return fmt.Sprintf("%d-W%02d", y, w)
+}
+
+// boundaries adds the period's start and end as local and UTC timestamps
+// with the zone's UTC offset at each.
+func (p digestPeriod) boundaries(into map[string]interface{}) map[string]interface{} {
+	into["period_start_local"] = p.Start.Format(time.RFC3339)
+	into["period_end_local"] = p.End.Format(time.RFC3339)
+	into["period_start_utc"] = p.Start.UTC().Format(time.RFC3339)
+	into["period_end_utc"] = p.End.UTC().Format(time.RFC3339)
+	into["period_start_offset"] = p.Start.Format("-07:00")
+	into["period_end_offset"] = p.End.Format("-07:00")
+	return into
+}
+
+// payload adds the period to a single-zone trigger payload.
+func (p digestPeriod) payload(into map[string]interface{}) {
+	if p.Kind == "" {
+		return
+	}
+	into["period"], into["iso_week"] = p.Kind, p.isoWeek()
+	into["period_start"], into["period_end"] = p.dates()
+	p.boundaries(into)
+	p.Fiscal.payload(into)
+}
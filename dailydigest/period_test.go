@@ -0,0 +1,147 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPeriodForAcrossDST(t *testing.T) {
+	monthly := schedule{CType: TypeMonthlyAt9A, Hour: 9, Period: periodMonth, Anchor: &periodAnchor{Kind: anchorFirstBusinessDay}}
+	lastDay := schedule{CType: TypeMonthlyAt9A, Hour: 9, Period: periodMonth, Anchor: &periodAnchor{Kind: anchorLastDay}}
+	lastFriday := schedule{CType: TypeMonthlyAt9A, Hour: 9, Period: periodMonth, Anchor: &periodAnchor{Kind: anchorNthWeekday, N: -1, Weekday: "friday"}}
+	tests := []struct {
+		name       string
+		zone       string
+		s          schedule
+		due        string // local date the digest fired on
+		start, end string
+	}{
+		{"month with spring forward", "Europe/Berlin", monthly, "2026-04-01",
+			"2026-03-01T00:00:00+01:00", "2026-04-01T00:00:00+02:00"},
+		{"month with fall back", "America/New_York", lastDay, "2026-11-30",
+			"2026-11-01T00:00:00-04:00", "2026-12-01T00:00:00-05:00"},
+		{"last friday with fall back", "Europe/London", lastFriday, "2026-10-30",
+			"2026-10-01T00:00:00+01:00", "2026-11-01T00:00:00Z"},
+		{"southern quarter", "Australia/Sydney", quarterlySchedule, "2026-07-01",
+			"2026-04-01T00:00:00+11:00", "2026-07-01T00:00:00+10:00"},
+		// Chile skips midnight on 2026-09-06; the next month still starts
+		// at midnight.
+		{"month with skipped midnight", "America/Santiago", monthly, "2026-10-01",
+			"2026-09-01T00:00:00-04:00", "2026-10-01T00:00:00-03:00"},
+		{"skipped midnight day", "America/Santiago", dailySchedule, "2026-09-06",
+			"2026-09-06T01:00:00-03:00", "2026-09-07T00:00:00-03:00"},
+		{"week with spring forward", "Europe/Berlin", weeklySchedule, "2026-03-30",
+			"2026-03-23T00:00:00+01:00", "2026-03-30T00:00:00+02:00"},
+		// the day before a skipped midnight ends when the next day starts.
+		{"day before skipped midnight", "America/Santiago", dailySchedule, "2026-09-05",
+			"2026-09-05T00:00:00-04:00", "2026-09-06T01:00:00-03:00"},
+		{"day before skipped midnight in Havana", "America/Havana", dailySchedule, "2026-03-07",
+			"2026-03-07T00:00:00-05:00", "2026-03-08T01:00:00-04:00"},
+		{"week ending at a skipped midnight", "America/Santiago", weeklySchedule, "2026-09-07",
+			"2026-08-31T00:00:00-04:00", "2026-09-07T00:00:00-03:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc, err := loadLocation(tt.zone)
+			if err != nil {
+				t.Fatal(err)
+			}
+			due, _ := time.ParseInLocation(time.DateOnly, tt.due, loc)
+			due = due.Add(9 * time.Hour)
+			p := (&tenant{}).periodFor(occurrence{Zone: tt.zone, Schedule: tt.s, Due: due})
+			if got := p.Start.Format(time.RFC3339); got != tt.start {
+				t.Errorf("start = %s, want %s", got, tt.start)
+			}
+			if got := p.End.Format(time.RFC3339); got != tt.end {
+				t.Errorf("end = %s, want %s", got, tt.end)
+			}
+		})
+	}
+}
+
+func TestSundayWeekAcrossSkippedMidnight(t *testing.T) {
+	// a Sunday-to-Thursday work week puts the skipped midnight at a week
+	// boundary.
+	tn := &tenant{workWeeks: workWeekOverrides{"*": sunThu}}
+	tests := []struct {
+		zone       string
+		due        string
+		start, end string
+	}{
+		{"America/Santiago", "2026-09-13", "2026-09-06T01:00:00-03:00", "2026-09-13T00:00:00-03:00"},
+		{"America/Santiago", "2026-09-06", "2026-08-30T00:00:00-04:00", "2026-09-06T01:00:00-03:00"},
+		{"America/Havana", "2026-03-08", "2026-03-01T00:00:00-05:00", "2026-03-08T01:00:00-04:00"},
+	}
+	for _, tt := range tests {
+		loc, err := loadLocation(tt.zone)
+		if err != nil {
+			t.Fatal(err)
+		}
+		due, _ := time.ParseInLocation(time.DateOnly, tt.due, loc)
+		due = due.Add(9 * time.Hour)
+		p := tn.periodFor(occurrence{Zone: tt.zone, Schedule: weeklySchedule, Due: due})
+		if got := p.Start.Format(time.RFC3339); got != tt.start {
+			t.Errorf("%s %s: start = %s, want %s", tt.zone, tt.due, got, tt.start)
+		}
+		if got := p.End.Format(time.RFC3339); got != tt.end {
+			t.Errorf("%s %s: end = %s, want %s", tt.zone, tt.due, got, tt.end)
+		}
+		if ps, pe := p.dates(); pe < ps {
+			t.Errorf("%s %s: period_end %s before period_start %s", tt.zone, tt.due, pe, ps)
+		}
+	}
+}
+
+func TestAnchorDueAcrossDST(t *testing.T) {
+	tn := &tenant{}
+	tests := []struct {
+		zone string
+		s    schedule
+		at   string // local time
+		want bool
+	}{
+		// March 1 2026 is a Sunday; the first business day is March 2.
+		{"Europe/Berlin", monthlySchedule, "2026-03-01T09:05", false},
+		{"Europe/Berlin", monthlySchedule, "2026-03-02T09:05", true},
+		// DST ends in Sydney on April 5; the quarter still starts April 1.
+		{"Australia/Sydney", quarterlySchedule, "2026-04-01T09:05", true},
+		{"America/New_York", schedule{CType: TypeMonthlyAt9A, Hour: 9, Period: periodMonth, Anchor: &periodAnchor{Kind: anchorNthWeekday, N: 2, Weekday: "sunday"}}, "2026-03-08T09:05", true},
+		{"America/Santiago", schedule{CType: TypeMonthlyAt9A, Hour: 9, Period: periodMonth, Anchor: &periodAnchor{Kind: anchorNthWeekday, N: 1, Weekday: "sunday"}}, "2026-09-06T09:05", true},
+		{"America/Santiago", schedule{CType: TypeMonthlyAt9A, Hour: 9, Period: periodMonth, Anchor: &periodAnchor{Kind: anchorLastDay}}, "2026-09-30T09:05", true},
+		{"America/Santiago", schedule{CType: TypeMonthlyAt9A, Hour: 9, Period: periodMonth, Anchor: &periodAnchor{Kind: anchorLastDay}}, "2026-09-29T09:05", false},
+	}
+	for _, tt := range tests {
+		loc, err := loadLocation(tt.zone)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tLoc, _ := time.ParseInLocation("2006-01-02T15:04", tt.at, loc)
+		due, _, _ := tn.due(occurrence{Zone: tt.zone, Schedule: tt.s})(tLoc)
+		if due != tt.want {
+			t.Errorf("%s %s %s at %s: due = %v, want %v", tt.zone, tt.s.Anchor.Kind, tt.s.Period, tt.at, due, tt.want)
+		}
+	}
+}
+
+func TestLocalMidnight(t *testing.T) {
+	tests := []struct {
+		zone string
+		date string
+		want string
+	}{
+		{"Europe/Berlin", "2026-03-29", "2026-03-29T00:00:00+01:00"},
+		{"America/Santiago", "2026-09-06", "2026-09-06T01:00:00-03:00"},
+		{"America/Havana", "2026-03-08", "2026-03-08T01:00:00-04:00"},
+		{"America/Havana", "2026-11-01", "2026-11-01T00:00:00-04:00"},
+	}
+	for _, tt := range tests {
+		loc, err := loadLocation(tt.zone)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d := date(tt.date)
+		if got := localMidnight(d.Year(), d.Month(), d.Day(), loc).Format(time.RFC3339); got != tt.want {
+			t.Errorf("localMidnight(%s, %s) = %s, want %s", tt.zone, tt.date, got, tt.want)
+		}
+	}
+}
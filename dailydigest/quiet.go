@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	quietDrop  = "drop"
+	quietDefer = "defer"
+)
+
+// quietWindow is a stretch of local time in which no digest fires. An
+// occurrence that falls inside it is dropped, or with the "defer" policy
+// fired when the window ends. Windows may wrap midnight, e.g. 22:00-07:00.
+type quietWindow struct {
+	Start  string `json:"start"`
+	End    string `json:"end"`
+	Policy string `json:"policy,omitempty"`
+	// Zones limits the window to these IANA zones or abbreviation groups;
+	// empty applies it everywhere.
+	Zones []string `json:"zones,omitempty"`
+
+	start, end int // minutes after local midnight
+}
+
+// suppression is an occurrence that quiet hours or a holiday kept from
+// firing.
+type suppression struct {
+	Occurrence time.Time
+	Reason     string
+	Policy     string
+	Window     string
+	Holiday    string
+}
+
+// loadQuietHours reads the global windows from DIGEST_QUIET_HOURS, a secret
+// reference to a JSON array of windows.
+func loadQuietHours(ctx context.Context, c config) ([]quietWindow, error) {
+	if c.QuietHours == "" {
+		return nil, nil
+	}
+	data, err := loadSecret(ctx, c.QuietHours)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read quiet hours: %w", err)
+	}
+	var windows []quietWindow
+	if err := json.Unmarshal(data, &windows); err != nil {
+		return nil, fmt.Errorf("cannot decode quiet hours: %w", err)
+	}
+	return windows, prepareQuietWindows(windows)
+}
+
+func prepareQuietWindows(windows []quietWindow) error {
+	for i := range windows {
+		w := &windows[i]
+		sh, sm, err := parseLocalTime(w.Start)
+		if err != nil {
+			return fmt.Errorf("quiet window start: %w", err)
+		}
+		eh, em, err := parseLocalTime(w.End)
+		if err != nil {
+			return fmt.Errorf("quiet window end: %w", err)
+		}
+		w.start, w.end = sh*60+sm, eh*60+em
+		if w.start == w.end {
+			return fmt.Errorf("quiet window %s-%s is empty", w.Start, w.End)
+		}
+		switch w.Policy {
+		case "":
+			w.Policy = quietDrop
+		case quietDrop, quietDefer:
+		default:
+			return fmt.Errorf("unknown quiet window policy %q", w.Policy)
+		}
+	}
+	return nil
+}
+
+// appliesTo reports whether the window covers zone, found under group.
+func (w quietWindow) appliesTo(group, zone string) bool {
+	if len(w.Zones) == 0 {
+		return true
+	}
+	for _, z := range w.Zones {
+		if z == zone || z == group {
+			return true
+		}
+	}
+	return false
+}
+
+// contains reports whether local wall-clock time tLoc is inside the window.
+func (w quietWindow) contains(tLoc time.Time) bool {
+	m := tLoc.Hour()*60 + tLoc.Minute()
+	if w.start < w.end {
+		return m >= w.start && m < w.end
+	}
+	return m >= w.start || m < w.end
+}
+
+// quietDecision is what quiet hours make of one schedule at one instant.
+type quietDecision struct {
+	// Fire is set when the schedule fires now, either on time or, if
+	// DeferredFrom is non-zero, as an occurrence held back until a window
+	// ended.
+	Fire         bool
+	Due          time.Time
+	DeferredFrom time.Time
+	ShiftedFrom  time.Time
+	// Suppressed is set when an on-time occurrence fell inside a window or
+	// was blocked by due.
+	Suppressed *suppression
+}
+
+// dueFunc reports whether a schedule is due at local time x, which is on the
+// schedule's clock time. shiftedFrom is set when an earlier occurrence was
+// moved to x; blocked explains why a clock match is not due.
+type dueFunc func(x time.Time) (due bool, shiftedFrom time.Time, blocked *suppression)
+
+// applyQuietHours decides whether s fires at tLoc once windows, already
+// filtered for the zone, are taken into account. due decides which days the
+// schedule runs on.
+func applyQuietHours(tLoc time.Time, s schedule, windows []quietWindow, due dueFunc) quietDecision {
+	var d quietDecision
+	clock := schedule{Hour: s.Hour, Minute: s.Minute}
+	if clock.matches(tLoc) {
+		occ := clock.occurrence(tLoc)
+		ok, shifted, blocked := due(occ)
+		d.Fire, d.Due, d.ShiftedFrom, d.Suppressed = ok, occ, shifted, blocked
+		for _, w := range windows {
+			if ok && w.contains(occ) {
+				d.Fire = false
+				d.Suppressed = &suppression{Occurrence: occ, Reason: "quiet_hours", Policy: w.Policy, Window: w.Start + "-" + w.End}
+				break
+			}
+		}
+	}
+	for _, w := range windows {
+		if w.Policy != quietDefer {
+			continue
+		}
+		end := schedule{Hour: w.end / 60, Minute: w.end % 60}
+		if !end.matches(tLoc) {
+			continue
+		}
+		// the last time s was due before the window ended.
+		windowEnd := end.occurrence(tLoc)
+		before := (w.end - s.Hour*60 - s.Minute + 24*60) % (24 * 60)
+		if before == 0 {
+			before = 24 * 60
+		}
+		occ := windowEnd.Add(-time.Duration(before) * time.Minute)
+		if !w.contains(occ) {
+			continue
+		}
+		if ok, shifted, _ := due(occ); ok {
+			d.Fire, d.Due, d.DeferredFrom, d.ShiftedFrom = true, occ, occ, shifted
+			break
+		}
+	}
+	return d
+}
+
+// quietWindowsFor returns the tenant's windows that cover zone.
+func (tn *tenant) quietWindowsFor(group, zone string) []quietWindow {
+	var out []quietWindow
+	for _, w := range tn.quiet {
+		if w.appliesTo(group, zone) {
+			out = append(out, w)
+		}
+	}
+	return out
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// catalogVersion identifies the contents of the timezones catalog, so a
+// report can be matched to the exact zone list that was evaluated.
+var catalogVersion = func() string {
+	abbrs := make([]string, 0, len(timezones))
+	for abbr := range timezones {
+		abbrs = append(abbrs, abbr)
+	}
+	sort.Strings(abbrs)
+	h := sha256.New()
+	for _, abbr := range abbrs {
+		fmt.Fprintf(h, "%s=%s\n", abbr, strings.Join(timezones[abbr], ","))
+	}
+	return hex.EncodeToString(h.Sum(nil))[:12]
+}()
+
+// runReport is the audit record of one invocation: what was evaluated, what
+// fired, and exactly what was sent.
+type runReport struct {
+	RunID          string           `json:"run_id"`
+	RequestID      string           `json:"request_id,omitempty"`
+	EvaluatedAt    time.Time        `json:"evaluated_at"`
+	CatalogVersion string           `json:"catalog_version"`
+	TZDataVersion  string           `json:"tzdata_version"`
+	Schedules      []reportSchedule `json:"schedules"`
+	Results        []reportResult   `json:"results"`
+	Deliveries     []reportDelivery `json:"deliveries"`
+	Suppressed     []plannedDigest  `json:"suppressed,omitempty"`
+	StartedAt      time.Time        `json:"started_at"`
+	FinishedAt     time.Time        `json:"finished_at"`
+	DurationMs     int64            `json:"duration_ms"`
+}
+
+// reportSchedule is a schedule as one tenant evaluated it.
+type reportSchedule struct {
+	Tenant string `json:"tenant"`
+	schedule
+}
+
+type reportResult struct {
+	Tenant  string `json:"tenant"`
+	Zone    string `json:"zone"`
+	Group   string `json:"group"`
+	CType   CType  `json:"type"`
+	Outcome string `json:"outcome"`
+	Error   string `json:"error,omitempty"`
+}
+
+type reportDelivery struct {
+	Tenant    string          `json:"tenant"`
+	Zones     []string        `json:"zones"`
+	CType     CType           `json:"type"`
+	Payload   json.RawMessage `json:"payload"`
+	Attempts  int             `json:"attempts"`
+	LatencyMs int64           `json:"latency_ms"`
+	Error     string          `json:"error,omitempty"`
+}
+
+func newRunReport(ctx context.Context, runID string, evaluatedAt time.Time, tenants []*tenant) *runReport {
+	r := &runReport{
+		RunID:          runID,
+		EvaluatedAt:    evaluatedAt,
+		CatalogVersion: catalogVersion,
+		TZDataVersion:  tzdataVersion,
+		StartedAt:      time.Now().UTC(),
+	}
+	for _, tn := range tenants {
+		for _, s := range tn.schedules() {
+			r.Schedules = append(r.Schedules, reportSchedule{Tenant: tn.ID, schedule: s})
+		}
+	}
+	if lc, ok := lambdacontext.FromContext(ctx); ok {
+		r.RequestID = lc.AwsRequestID
+	}
+	return r
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// reportKey places reports under their evaluation date so a question about a
+// given day only needs one prefix listing.
+func reportKey(r *runReport) string {
+	return path.Join("reports", r.EvaluatedAt.Format("2006/01/02"),
+		fmt.Sprintf("%s-%s.json", r.EvaluatedAt.Format("150405"), r.RunID))
+}
+
+// saveReport writes r to the sink named by DIGEST_REPORT_KIND: "file"
+// appends a JSON line to DIGEST_REPORT_TARGET, "s3" writes one object per
+// run under the "bucket/prefix" target, and "state" puts it in the state
+// store.
+func saveReport(ctx context.Context, c config, store stateStore, r *runReport) {
+	if c.ReportKind == "" || r == nil {
+		return
+	}
+	r.FinishedAt = time.Now().UTC()
+	r.DurationMs = r.FinishedAt.Sub(r.StartedAt).Milliseconds()
+	body, err := json.Marshal(r)
+	if err != nil {
+		slog.Error("cannot encode run report", "error", err)
+		return
+	}
+	switch c.ReportKind {
+	case "file":
+		err = appendLine(c.ReportTarget, body)
+	case "s3":
+		bucket, prefix, _ := strings.Cut(c.ReportTarget, "/")
+		var client *s3.Client
+		if client, err = newS3Client(ctx, c.S3Endpoint); err == nil {
+			err = (&s3Store{client: client, bucket: bucket, prefix: prefix}).Put(ctx, reportKey(r), body)
+		}
+	case "state":
+		if store == nil {
+			err = fmt.Errorf("report sink is the state store but none is configured")
+		} else {
+			err = store.Put(ctx, reportKey(r), body)
+		}
+	default:
+		err = fmt.Errorf("unknown report kind %q", c.ReportKind)
+	}
+	if err != nil {
+		slog.Error("cannot save run report", "error", err)
+		return
+	}
+	slog.Info("run report saved", "kind", c.ReportKind, "key", reportKey(r))
+}
+
+func appendLine(name string, line []byte) error {
+	file, err := os.OpenFile(name, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunReportFile(t *testing.T) {
+	srv := newDigestServer(t, failZones(http.StatusInternalServerError, "Europe/Paris"))
+	target := filepath.Join(t.TempDir(), "reports.jsonl")
+	useConfig(t, func(c *config) {
+		c.Endpoint = srv.URL
+		c.ReportKind, c.ReportTarget = "file", target
+	})
+	at := time.Date(2026, time.March, 2, 15, 5, 0, 0, time.UTC)
+	var runIDs []string
+	for i := 0; i < 2; i++ {
+		ctx, runID := startRun(t.Context())
+		runIDs = append(runIDs, runID)
+		if err := runDigest(ctx, at); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f, err := os.Open(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var reports []runReport
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 16*1024*1024)
+	for scanner.Scan() {
+		var r runReport
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatal(err)
+		}
+		reports = append(reports, r)
+	}
+	if len(reports) != 2 {
+		t.Fatalf("got %d reports, want one line per run", len(reports))
+	}
+
+	r := reports[0]
+	if !r.EvaluatedAt.Equal(at) || r.CatalogVersion != catalogVersion || r.TZDataVersion != tzdataVersion || r.RunID != runIDs[0] {
+		t.Errorf("report header = %s %s %s %q", r.EvaluatedAt, r.CatalogVersion, r.TZDataVersion, r.RunID)
+	}
+	if r.FinishedAt.Before(r.StartedAt) || r.DurationMs < 0 {
+		t.Errorf("report timings %s to %s, %dms", r.StartedAt, r.FinishedAt, r.DurationMs)
+	}
+	if len(r.Schedules) == 0 || r.Schedules[0].Tenant != defaultTenantID {
+		t.Errorf("schedules = %+v", r.Schedules)
+	}
+	if len(r.Results) != len(srv.payloads)/2 || len(r.Deliveries) != len(r.Results) {
+		t.Errorf("%d results and %d deliveries for %d posts per run", len(r.Results), len(r.Deliveries), len(srv.payloads)/2)
+	}
+	var paris *reportResult
+	for i, res := range r.Results {
+		if res.Zone == "Europe/Paris" {
+			paris = &r.Results[i]
+		} else if res.Outcome != "delivered" || res.Error != "" {
+			t.Errorf("%s = %s %q, want delivered", res.Zone, res.Outcome, res.Error)
+		}
+	}
+	if paris == nil || paris.Outcome != "failed" || (paris.Group != "CET" && paris.Group != "CEST") || paris.CType != TypeDailyAt4P || !strings.Contains(paris.Error, "500") {
+		t.Errorf("Europe/Paris result = %+v", paris)
+	}
+	for _, d := range r.Deliveries {
+		var p map[string]interface{}
+		if err := json.Unmarshal(d.Payload, &p); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := p["token"]; ok {
+			t.Errorf("report payload for %v carries the body token", d.Zones)
+		}
+		if p["zone"] != d.Zones[0] || d.Attempts != 1 {
+			t.Errorf("delivery %v: payload zone %v, %d attempts", d.Zones, p["zone"], d.Attempts)
+		}
+		if (d.Zones[0] == "Europe/Paris") != (d.Error != "") {
+			t.Errorf("delivery %v error = %q", d.Zones, d.Error)
+		}
+	}
+	if reports[1].RunID != runIDs[1] {
+		t.Errorf("second report has run %q, want %q", reports[1].RunID, runIDs[1])
+	}
+}
+
+func TestRunReportStateStore(t *testing.T) {
+	srv := newDigestServer(t, nil)
+	dir := t.TempDir()
+	useConfig(t, func(c *config) {
+		c.Endpoint = srv.URL
+		c.ReportKind = "state"
+		c.StateKind, c.StateTarget = "file", dir
+	})
+	at := time.Date(2026, time.March, 2, 15, 5, 0, 0, time.UTC)
+	ctx, runID := startRun(t.Context())
+	if err := runDigest(ctx, at); err != nil {
+		t.Fatal(err)
+	}
+	matches, _ := filepath.Glob(filepath.Join(dir, "reports", "2026", "03", "02", "150500-*.json"))
+	if len(matches) != 1 {
+		t.Fatalf("state store holds %v, want one report keyed by evaluation time", matches)
+	}
+	data, _ := os.ReadFile(matches[0])
+	var r runReport
+	if err := json.Unmarshal(data, &r); err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(matches[0]) != "150500-"+runID+".json" || r.RunID != runID || len(r.Results) == 0 {
+		t.Errorf("report %s has run %q and %d results", matches[0], r.RunID, len(r.Results))
+	}
+}
+
+func TestReportKey(t *testing.T) {
+	r := &runReport{RunID: "abc123", EvaluatedAt: time.Date(2026, time.December, 31, 23, 59, 7, 0, time.UTC)}
+	if got := reportKey(r); got != "reports/2026/12/31/235907-abc123.json" {
+		t.Errorf("reportKey = %s", got)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// schedule is a local wall-clock time at which a CType fires. A schedule
+// matches for triggerFrequency minutes after its time so that one of the
+// cron invocations lands inside the window. FirstWorkday fires on the first
+// day of each zone's work week instead of a fixed Weekday. Calendar-period
+// schedules fire once per Period, on the day Anchor picks. BusinessDays
+// limits any schedule to business days ("only") or moves occurrences that
+// fall on other days to the "next" or "previous" business day. Solar
+// replaces the clock time with one relative to sunrise or sunset.
+type schedule struct {
+	CType        CType         `json:"type"`
+	Hour         int           `json:"hour"`
+	Minute       int           `json:"minute"`
+	Weekday      *time.Weekday `json:"weekday,omitempty"`
+	FirstWorkday bool          `json:"first_workday,omitempty"`
+	Period       string        `json:"period,omitempty"`
+	Anchor       *periodAnchor `json:"anchor,omitempty"`
+	BusinessDays string        `json:"business_days,omitempty"`
+	Solar        *solarAnchor  `json:"solar,omitempty"`
+}
+
+// occurrence returns the start of the window tLoc matched in, i.e. the local
+// time the schedule was due. Only meaningful when s.matches(tLoc).
+func (s schedule) occurrence(tLoc time.Time) time.Time {
+	const day = 24 * 60
+	elapsed := (tLoc.Hour()*60 + tLoc.Minute() - s.Hour*60 - s.Minute + day) % day
+	return tLoc.Add(-time.Duration(elapsed) * time.Minute).Truncate(time.Minute)
+}
+
+// localTime is the schedule's wall-clock time as "HH:MM".
+func (s schedule) localTime() string {
+	return fmt.Sprintf("%02d:%02d", s.Hour, s.Minute)
+}
+
+// at returns a copy of s moved to hour:minute, which replaces any solar
+// anchor.
+func (s schedule) at(hour, minute int) schedule {
+	s.Hour, s.Minute, s.Solar = hour, minute, nil
+	return s
+}
+
+// parseLocalTime parses an "HH:MM" wall-clock time.
+func parseLocalTime(v string) (hour, minute int, err error) {
+	t, err := time.Parse("15:04", v)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid local time %q, want HH:MM", v)
+	}
+	return t.Hour(), t.Minute(), nil
+}
+
+func weekday(d time.Weekday) *time.Weekday {
+	return &d
+}
+
+var (
+	dailySchedule  = schedule{CType: TypeDailyAt4P, Hour: 16}
+	weeklySchedule = schedule{CType: TypeWeeklyAt9A, Hour: 9, Weekday: weekday(time.Monday)}
+)
+
+// matches reports whether tLoc, already in the zone's location, falls in the
+// schedule's window.
+func (s schedule) matches(tLoc time.Time) bool {
+	const day = 24 * 60
+	elapsed := (tLoc.Hour()*60 + tLoc.Minute() - s.Hour*60 - s.Minute + day) % day
+	if elapsed > triggerFrequency {
+		return false
+	}
+	// a window that started just before midnight belongs to the previous day.
+	started := tLoc.Add(-time.Duration(elapsed) * time.Minute)
+	return s.Weekday == nil || started.Weekday() == *s.Weekday
+}
+
+// locations caches loaded zones, which are parsed from zoneinfo on every
+// load, across tenants and warm invocations.
+var locations sync.Map
+
+func loadLocation(name string) (*time.Location, error) {
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+	// the embedded tzdata first; the runtime's for anything it lacks.
+	loc, ok, err := loadEmbeddedLocation(name)
+	if !ok {
+		loc, err = time.LoadLocation(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	locations.Store(name, loc)
+	return loc, nil
+}
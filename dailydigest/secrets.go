@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+)
+
+var (
+	secretsMu     sync.Mutex
+	secretsClient *secretsmanager.Client
+)
+
+// secretsManager returns the shared Secrets Manager client, creating it on
+// first use. A failed attempt is not cached so the next call tries again.
+func secretsManager(ctx context.Context) (*secretsmanager.Client, error) {
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	if secretsClient != nil {
+		return secretsClient, nil
+	}
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	secretsClient = secretsmanager.NewFromConfig(awsCfg)
+	return secretsClient, nil
+}
+
+// loadSecret resolves a secret reference from the environment:
+//
+//	file:/path/to/cert.pem   read from a file, e.g. a mounted layer
+//	secret:<id or arn>       read from AWS Secrets Manager
+//	anything else            the value itself, e.g. an inline PEM block
+//
+// References are resolved on every call so rotated secrets are picked up.
+func loadSecret(ctx context.Context, ref string) ([]byte, error) {
+	switch {
+	case strings.HasPrefix(ref, "file:"):
+		return os.ReadFile(strings.TrimPrefix(ref, "file:"))
+	case strings.HasPrefix(ref, "secret:"):
+		client, err := secretsManager(ctx)
+		if err != nil {
+			return nil, err
+		}
+		id := strings.TrimPrefix(ref, "secret:")
+		out, err := client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{SecretId: aws.String(id)})
+		if err != nil {
+			return nil, fmt.Errorf("cannot read secret %s: %w", id, err)
+		}
+		if out.SecretString != nil {
+			return []byte(*out.SecretString), nil
+		}
+		return out.SecretBinary, nil
+	default:
+		return []byte(ref), nil
+	}
+}
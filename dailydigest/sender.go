@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// statusError is returned by post when the endpoint answers with a non-2xx
+// status.
+type statusError struct {
+	Code   int
+	Status string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("digest endpoint returned %s", e.Status)
+}
+
+// sender wraps post with retries. Only transient failures are retried: network
+// errors, 429 and 5xx. Other 4xx answers will not change on a second try.
+// Every attempt goes through the target's circuit breaker.
+type sender struct {
+	cfg      config
+	attempts int
+	backoff  time.Duration
+}
+
+func newSender(c config) *sender {
+	attempts := c.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	return &sender{cfg: c, attempts: attempts, backoff: c.RetryBackoff}
+}
+
+// send posts payload to the tenant's endpoint and reports how many attempts
+// it took.
+func (s *sender) send(ctx context.Context, tn *tenant, payload []byte) ([]byte, int, error) {
+	var (
+		respBody []byte
+		err      error
+	)
+	cb := breakerFor(s.cfg, tn.Endpoint)
+	for attempt := 1; attempt <= s.attempts; attempt++ {
+		if !cb.allow(time.Now()) {
+			return respBody, attempt - 1, errors.Join(errBreakerOpen, err)
+		}
+		respBody, err = post(ctx, tn, payload)
+		if err != nil && ctx.Err() != nil {
+			// the invocation ran out, which says nothing about the endpoint.
+			cb.abandon()
+			return respBody, attempt, err
+		}
+		cb.record(time.Now(), breakerOutcome(err))
+		if err == nil || !retryable(err) || attempt == s.attempts {
+			return respBody, attempt, err
+		}
+		wait := s.backoff << (attempt - 1)
+		slog.Warn("delivery attempt failed, retrying", "tenant", tn.ID, "attempt", attempt, "wait", wait, "error", err, "outcome", "retry")
+		select {
+		case <-ctx.Done():
+			return respBody, attempt, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+	return respBody, s.attempts, err
+}
+
+func retryable(err error) bool {
+	var se *statusError
+	if errors.As(err, &se) {
+		return se.Code == http.StatusTooManyRequests || se.Code >= 500
+	}
+	return !errors.Is(err, context.Canceled)
+}
+
+// breakerOutcome keeps 4xx answers from tripping the breaker: the endpoint is
+// up, it just did not like that one request.
+func breakerOutcome(err error) error {
+	if err == nil || retryable(err) {
+		return err
+	}
+	return nil
+}
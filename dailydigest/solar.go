@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	solarSunrise = "sunrise"
+	solarSunset  = "sunset"
+	solarNoon    = "noon"
+)
+
+// solarAnchor ties a schedule to the sun at the zone's representative
+// location instead of a clock time, e.g. {"event": "sunrise", "offset":
+// "-30m"}. On days the sun neither rises nor sets there, Polar "skip" (the
+// default) drops the occurrence and "clock" fires at the schedule's hour and
+// minute instead.
+type solarAnchor struct {
+	Event  string `json:"event"`
+	Offset string `json:"offset,omitempty"`
+	Polar  string `json:"polar,omitempty"`
+
+	offset time.Duration
+}
+
+func (a *solarAnchor) prepare() error {
+	switch a.Event {
+	case solarSunrise, solarSunset, solarNoon:
+	default:
+		return fmt.Errorf("unknown solar event %q", a.Event)
+	}
+	switch a.Polar {
+	case "", "skip", "clock":
+	default:
+		return fmt.Errorf("solar polar must be skip or clock")
+	}
+	if a.Offset != "" {
+		d, err := time.ParseDuration(a.Offset)
+		if err != nil || d <= -12*time.Hour || d >= 12*time.Hour {
+			return fmt.Errorf("invalid solar offset %q", a.Offset)
+		}
+		a.offset = d
+	}
+	return nil
+}
+
+// sunState says whether the sun crosses the horizon on a day.
+type sunState int
+
+const (
+	sunNormal sunState = iota
+	polarDay
+	polarNight
+	// noEvent is a day next to a polar period on which the event falls on
+	// the neighbouring local date.
+	noEvent
+)
+
+func (s sunState) String() string {
+	switch s {
+	case polarDay:
+		return "polar_day"
+	case polarNight:
+		return "polar_night"
+	case noEvent:
+		return "no_solar_event"
+	}
+	return "normal"
+}
+
+// sunEvent returns the UTC instant of event on the given civil date at lat,
+// lon, using the NOAA solar calculator equations. Sunrise and sunset use the
+// standard 90.833° zenith, which allows for refraction and the solar disc.
+// At polar latitudes it reports polarDay or polarNight instead when the sun
+// stays above or below the horizon all day; solar noon always exists.
+func sunEvent(y int, m time.Month, d int, lat, lon float64, event string) (time.Time, sunState) {
+	midnight := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	// start from local solar noon and refine once at the event itself.
+	minutes := 720 - 4*lon
+	for i := 0; i < 2; i++ {
+		jd := julianDay(midnight) + minutes/1440
+		decl, eqTime := solarPosition((jd - 2451545) / 36525)
+		noon := 720 - 4*lon - eqTime
+		if event == solarNoon {
+			minutes = noon
+			continue
+		}
+		cosHA := math.Cos(rad(90.833))/(math.Cos(rad(lat))*math.Cos(decl)) - math.Tan(rad(lat))*math.Tan(decl)
+		switch {
+		case cosHA > 1:
+			return time.Time{}, polarNight
+		case cosHA < -1:
+			return time.Time{}, polarDay
+		}
+		ha := deg(math.Acos(cosHA))
+		if event == solarSunrise {
+			minutes = noon - 4*ha
+		} else {
+			minutes = noon + 4*ha
+		}
+	}
+	return midnight.Add(time.Duration(minutes * float64(time.Minute))).Truncate(time.Second), sunNormal
+}
+
+// localSunEvent returns event on the local date of day in day's location.
+// The NOAA equations work on UTC dates, so the neighbouring ones are tried
+// for zones whose clocks are far from their solar time.
+func localSunEvent(day time.Time, info zoneInfo, event string) (time.Time, sunState) {
+	state := noEvent
+	for _, k := range []int{0, -1, 1} {
+		d := day.AddDate(0, 0, k)
+		at, st := sunEvent(d.Year(), d.Month(), d.Day(), info.Lat, info.Lon, event)
+		if st != sunNormal {
+			if k == 0 {
+				state = st
+			}
+			continue
+		}
+		if local := at.In(day.Location()); sameDate(local, day) {
+			return local, sunNormal
+		}
+	}
+	return time.Time{}, state
+}
+
+func julianDay(t time.Time) float64 {
+	return float64(t.Unix())/86400 + 2440587.5
+}
+
+// solarPosition returns the sun's declination in radians and the equation of
+// time in minutes for julian century t.
+func solarPosition(t float64) (decl, eqTime float64) {
+	l0 := math.Mod(280.46646+t*(36000.76983+t*0.0003032), 360)
+	m := 357.52911 + t*(35999.05029-0.0001537*t)
+	e := 0.016708634 - t*(0.000042037+0.0000001267*t)
+	c := math.Sin(rad(m))*(1.914602-t*(0.004817+0.000014*t)) +
+		math.Sin(rad(2*m))*(0.019993-0.000101*t) +
+		math.Sin(rad(3*m))*0.000289
+	omega := 125.04 - 1934.136*t
+	lambda := l0 + c - 0.00569 - 0.00478*math.Sin(rad(omega))
+	eps0 := 23 + (26+(21.448-t*(46.815+t*(0.00059-t*0.001813)))/60)/60
+	eps := eps0 + 0.00256*math.Cos(rad(omega))
+	decl = math.Asin(math.Sin(rad(eps)) * math.Sin(rad(lambda)))
+	yy := math.Pow(math.Tan(rad(eps/2)), 2)
+	eqTime = 4 * deg(yy*math.Sin(2*rad(l0))-2*e*math.Sin(rad(m))+
+		4*e*yy*math.Sin(rad(m))*math.Cos(2*rad(l0))-
+		0.5*yy*yy*math.Sin(4*rad(l0))-1.25*e*e*math.Sin(2*rad(m)))
+	return decl, eqTime
+}
+
+func rad(d float64) float64 { return d * math.Pi / 180 }
+func deg(r float64) float64 { return r * 180 / math.Pi }
+
+// solarSchedule resolves a solar schedule for zone at tLoc. It returns the
+// schedule moved to the clock time of the event plus offset when tLoc falls
+// in that time's window, and false otherwise. Polar days and zones without
+// coordinates are handled at the schedule's own clock time: a suppression is
+// returned, or with Polar "clock" the schedule unchanged.
+func solarSchedule(s schedule, zone string, tLoc time.Time) (schedule, bool, *suppression) {
+	a := s.Solar
+	reason := "no_coordinates"
+	if info, ok := zoneInfos[zone]; ok {
+		reason = ""
+		// the offset can move an event onto the neighbouring day.
+		for _, k := range []int{0, -1, 1} {
+			at, state := localSunEvent(tLoc.AddDate(0, 0, k), info, a.Event)
+			if state != sunNormal {
+				if k == 0 {
+					reason = state.String()
+				}
+				continue
+			}
+			fireAt := at.Add(a.offset)
+			if elapsed := tLoc.Sub(fireAt); elapsed >= 0 && elapsed <= triggerFrequency*time.Minute {
+				s.Hour, s.Minute = fireAt.Hour(), fireAt.Minute()
+				return s, true, nil
+			}
+		}
+	}
+	clock := schedule{Hour: s.Hour, Minute: s.Minute}
+	if reason == "" || !clock.matches(tLoc) {
+		return s, false, nil
+	}
+	if a.Polar == "clock" {
+		return s, true, nil
+	}
+	return s, false, &suppression{Occurrence: clock.occurrence(tLoc), Reason: reason, Policy: "skip"}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLocalSunEvent(t *testing.T) {
+	tests := []struct {
+		zone  string
+		date  string
+		event string
+		want  string // local HH:MM, NOAA solar calculator
+	}{
+		{"Europe/Paris", "2026-06-21", solarSunrise, "05:47"},
+		{"Europe/Paris", "2026-06-21", solarSunset, "21:58"},
+		{"America/New_York", "2026-12-21", solarSunrise, "07:17"},
+		{"America/New_York", "2026-12-21", solarSunset, "16:32"},
+		{"Australia/Sydney", "2026-12-21", solarSunrise, "05:41"},
+		{"Asia/Tokyo", "2026-03-20", solarNoon, "11:47"},
+		// an alias has the coordinates of the zone it stands for.
+		{"Japan", "2026-03-20", solarNoon, "11:47"},
+		{"Arctic/Longyearbyen", "2026-03-20", solarSunrise, "05:50"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.zone+"/"+tt.event+"/"+tt.date, func(t *testing.T) {
+			info, ok := zoneInfos[tt.zone]
+			if !ok {
+				t.Fatalf("no coordinates for %s", tt.zone)
+			}
+			loc, err := loadLocation(tt.zone)
+			if err != nil {
+				t.Fatal(err)
+			}
+			day, _ := time.ParseInLocation(time.DateOnly, tt.date, loc)
+			at, state := localSunEvent(day.Add(12*time.Hour), info, tt.event)
+			if state != sunNormal {
+				t.Fatalf("state = %s, want normal", state)
+			}
+			want, _ := time.ParseInLocation(time.DateOnly+" 15:04", tt.date+" "+tt.want, loc)
+			if d := at.Sub(want); d < -2*time.Minute || d > 2*time.Minute {
+				t.Errorf("%s = %s, want %s", tt.event, at.Format("15:04:05"), tt.want)
+			}
+		})
+	}
+}
+
+func TestPolarDayAndNight(t *testing.T) {
+	tests := []struct {
+		zone string
+		date string
+		want sunState
+	}{
+		{"Arctic/Longyearbyen", "2026-06-21", polarDay},
+		{"Arctic/Longyearbyen", "2026-12-21", polarNight},
+		{"Arctic/Longyearbyen", "2026-03-20", sunNormal},
+		{"Antarctica/Troll", "2026-12-21", polarDay},
+		{"Antarctica/Troll", "2026-06-21", polarNight},
+		{"Antarctica/Troll", "2026-09-22", sunNormal},
+	}
+	for _, tt := range tests {
+		info := zoneInfos[tt.zone]
+		d := date(tt.date)
+		for _, event := range []string{solarSunrise, solarSunset} {
+			if _, state := sunEvent(d.Year(), d.Month(), d.Day(), info.Lat, info.Lon, event); state != tt.want {
+				t.Errorf("%s %s on %s: state = %s, want %s", tt.zone, event, tt.date, state, tt.want)
+			}
+		}
+		if _, state := sunEvent(d.Year(), d.Month(), d.Day(), info.Lat, info.Lon, solarNoon); state != sunNormal {
+			t.Errorf("%s noon on %s: state = %s, want normal", tt.zone, tt.date, state)
+		}
+	}
+}
+
+func TestSolarSchedulePolar(t *testing.T) {
+	tests := []struct {
+		zone   string
+		at     string // local time at the schedule's clock time
+		polar  string
+		fire   bool
+		reason string
+	}{
+		{"Arctic/Longyearbyen", "2026-06-21T07:00", "", false, "polar_day"},
+		{"Arctic/Longyearbyen", "2026-12-21T07:00", "", false, "polar_night"},
+		{"Arctic/Longyearbyen", "2026-12-21T07:00", "clock", true, ""},
+		{"Antarctica/Troll", "2026-12-21T07:00", "", false, "polar_day"},
+		{"Antarctica/Troll", "2026-06-21T07:00", "clock", true, ""},
+		{"Etc/GMT-1", "2026-06-21T07:00", "", false, "no_coordinates"},
+		// coordinates through backward.tab; sunrise is long past at 07:00.
+		{"Israel", "2026-06-21T07:00", "", false, ""},
+	}
+	for _, tt := range tests {
+		loc, err := loadLocation(tt.zone)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tLoc, _ := time.ParseInLocation("2006-01-02T15:04", tt.at, loc)
+		s := schedule{CType: TypeDailyAt4P, Hour: 7, Solar: &solarAnchor{Event: solarSunrise, Polar: tt.polar}}
+		_, fire, sup := solarSchedule(s, tt.zone, tLoc)
+		reason := ""
+		if sup != nil {
+			reason = sup.Reason
+		}
+		if fire != tt.fire || reason != tt.reason {
+			t.Errorf("%s at %s polar %q: fire = %v reason %q, want %v %q", tt.zone, tt.at, tt.polar, fire, reason, tt.fire, tt.reason)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// errNotFound is returned by stateStore.Get for a key that was never written.
+var errNotFound = errors.New("state: key not found")
+
+// stateStore is a small key/value store shared between invocations and, when
+// backed by S3, between concurrent Lambda instances.
+type stateStore interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Put(ctx context.Context, key string, value []byte) error
+}
+
+// newStateStore builds the store named by DIGEST_STATE_KIND. It returns nil
+// when no shared store is configured.
+func newStateStore(ctx context.Context, c config) (stateStore, error) {
+	switch c.StateKind {
+	case "":
+		return nil, nil
+	case "file":
+		return &fileStore{dir: c.StateTarget}, nil
+	case "s3":
+		bucket, prefix, _ := strings.Cut(c.StateTarget, "/")
+		client, err := newS3Client(ctx, c.S3Endpoint)
+		if err != nil {
+			return nil, err
+		}
+		return &s3Store{client: client, bucket: bucket, prefix: prefix}, nil
+	default:
+		return nil, fmt.Errorf("unknown state store kind %q", c.StateKind)
+	}
+}
+
+// fileStore keeps one file per key under dir. Keys may contain slashes.
+type fileStore struct {
+	dir string
+}
+
+func (f *fileStore) Get(ctx context.Context, key string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(f.dir, filepath.FromSlash(key)))
+	if os.IsNotExist(err) {
+		return nil, errNotFound
+	}
+	return data, err
+}
+
+func (f *fileStore) Put(ctx context.Context, key string, value []byte) error {
+	name := filepath.Join(f.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+	tmp := name + ".tmp"
+	if err := os.WriteFile(tmp, value, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, name)
+}
+
+// s3Store keeps one object per key under prefix.
+type s3Store struct {
+	client *s3.Client
+	bucket string
+	prefix string
+}
+
+func (b *s3Store) Get(ctx context.Context, key string) ([]byte, error) {
+	out, err := b.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(path.Join(b.prefix, key)),
+	})
+	var noKey *types.NoSuchKey
+	if errors.As(err, &noKey) {
+		return nil, errNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer out.Body.Close()
+	return io.ReadAll(out.Body)
+}
+
+func (b *s3Store) Put(ctx context.Context, key string, value []byte) error {
+	_, err := b.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(path.Join(b.prefix, key)),
+		Body:   bytes.NewReader(value),
+	})
+	return err
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// subscription is one active subscriber: the zone they receive digests in
+// and, optionally, the local time they prefer for Type, which defaults to
+// the daily digest. A preference only moves its own type; the subscriber's
+// other types stay on the tenant's schedule. An empty Tenant applies to
+// every tenant.
+type subscription struct {
+	Tenant    string `json:"tenant,omitempty"`
+	User      string `json:"user,omitempty"`
+	Zone      string `json:"zone"`
+	LocalTime string `json:"local_time,omitempty"`
+	Type      CType  `json:"type,omitempty"`
+}
+
+type zoneKey struct {
+	Tenant string
+	Zone   string
+}
+
+type bucketKey struct {
+	zoneKey
+	CType CType
+}
+
+type clock struct {
+	Hour, Minute int
+}
+
+// subscriptionSet indexes subscriptions by tenant and zone, and buckets
+// preferred local times per (zone, CType) so evaluating a zone only looks at
+// its handful of distinct times, however many subscribers share them. A nil
+// set places no restriction on evaluation.
+type subscriptionSet struct {
+	byTenant map[string]map[string]bool
+	// users are the subscribers in each zone and preferred those of them
+	// with a preferred time for a CType; everyone else gets the tenant's
+	// schedule for that CType. Entries without a user each count as their
+	// own subscriber.
+	users     map[zoneKey]map[string]bool
+	preferred map[bucketKey]map[string]bool
+	buckets   map[bucketKey][]clock
+}
+
+func newSubscriptionSet(subs []subscription) *subscriptionSet {
+	s := &subscriptionSet{
+		byTenant:  map[string]map[string]bool{},
+		users:     map[zoneKey]map[string]bool{},
+		preferred: map[bucketKey]map[string]bool{},
+		buckets:   map[bucketKey][]clock{},
+	}
+	seen := map[bucketKey]map[clock]bool{}
+	for i, sub := range subs {
+		if s.byTenant[sub.Tenant] == nil {
+			s.byTenant[sub.Tenant] = map[string]bool{}
+		}
+		s.byTenant[sub.Tenant][sub.Zone] = true
+		zk := zoneKey{sub.Tenant, sub.Zone}
+		user := sub.User
+		if user == "" {
+			user = fmt.Sprintf("#%d", i)
+		}
+		if s.users[zk] == nil {
+			s.users[zk] = map[string]bool{}
+		}
+		s.users[zk][user] = true
+		if sub.LocalTime == "" {
+			continue
+		}
+		hour, minute, err := parseLocalTime(sub.LocalTime)
+		if err != nil {
+			slog.Warn("ignoring subscriber preference", "tenant", sub.Tenant, "user", sub.User, "zone", sub.Zone, "error", err)
+			continue
+		}
+		cType := sub.Type
+		if cType == "" {
+			cType = TypeDailyAt4P
+		}
+		bk := bucketKey{zk, cType}
+		if s.preferred[bk] == nil {
+			s.preferred[bk] = map[string]bool{}
+		}
+		s.preferred[bk][user] = true
+		c := clock{hour, minute}
+		if seen[bk] == nil {
+			seen[bk] = map[clock]bool{}
+		}
+		if !seen[bk][c] {
+			seen[bk][c] = true
+			s.buckets[bk] = append(s.buckets[bk], c)
+		}
+	}
+	return s
+}
+
+// schedulesFor returns the schedules to evaluate for zone: def when some
+// subscriber has no preferred time for def's CType, plus one per distinct
+// preferred local time for it.
+func (s *subscriptionSet) schedulesFor(tenantID, zone string, def schedule) []schedule {
+	if s == nil {
+		return []schedule{def}
+	}
+	var out []schedule
+	added := map[clock]bool{}
+	for _, id := range []string{"", tenantID} {
+		zk := zoneKey{id, zone}
+		bk := bucketKey{zk, def.CType}
+		if len(s.users[zk]) > len(s.preferred[bk]) && !added[clock{def.Hour, def.Minute}] {
+			added[clock{def.Hour, def.Minute}] = true
+			out = append(out, def)
+		}
+		for _, c := range s.buckets[bk] {
+			if !added[c] {
+				added[c] = true
+				out = append(out, def.at(c.Hour, c.Minute))
+			}
+		}
+	}
+	return out
+}
+
+// has reports whether zone has at least one subscriber for tenantID.
+func (s *subscriptionSet) has(tenantID, zone string) bool {
+	if s == nil {
+		return true
+	}
+	return s.byTenant[""][zone] || s.byTenant[tenantID][zone]
+}
+
+// subscriptionCache keeps the last fetched set across warm invocations and
+// refreshes it once it is older than the configured TTL.
+var subscriptionCache struct {
+	mu      sync.Mutex
+	set     *subscriptionSet
+	fetched time.Time
+}
+
+// loadSubscriptions returns the active subscriptions named by
+// DIGEST_SUBSCRIPTIONS:
+//
+//	file:/path/subs.json      a local JSON array
+//	https://host/subscribers  a JSON array served over HTTP
+//	state:<key>               a JSON array kept in the state store
+//
+// If a refresh fails the previous set is kept. With no set at all it returns
+// nil, so digests keep firing for the whole catalog rather than for nobody.
+func loadSubscriptions(ctx context.Context, c config, store stateStore) *subscriptionSet {
+	if c.Subscriptions == "" {
+		return nil
+	}
+	subscriptionCache.mu.Lock()
+	defer subscriptionCache.mu.Unlock()
+	if subscriptionCache.set != nil && time.Since(subscriptionCache.fetched) < c.SubscriptionsTTL {
+		return subscriptionCache.set
+	}
+	subs, err := fetchSubscriptions(ctx, c, store)
+	if err != nil {
+		if subscriptionCache.set != nil {
+			slog.Warn("cannot refresh subscriptions, keeping previous set", "error", err, "age", time.Since(subscriptionCache.fetched))
+		} else {
+			slog.Error("cannot load subscriptions, evaluating the whole catalog", "error", err)
+		}
+		return subscriptionCache.set
+	}
+	subscriptionCache.set, subscriptionCache.fetched = newSubscriptionSet(subs), time.Now()
+	slog.Info("subscriptions refreshed", "subscriptions", len(subs))
+	return subscriptionCache.set
+}
+
+func fetchSubscriptions(ctx context.Context, c config, store stateStore) ([]subscription, error) {
+	var (
+		data []byte
+		err  error
+	)
+	switch src := c.Subscriptions; {
+	case strings.HasPrefix(src, "file:"):
+		data, err = os.ReadFile(strings.TrimPrefix(src, "file:"))
+	case strings.HasPrefix(src, "http://"), strings.HasPrefix(src, "https://"):
+		data, err = fetchURL(ctx, src, c.Timeout)
+	case strings.HasPrefix(src, "state:"):
+		if store == nil {
+			return nil, fmt.Errorf("subscriptions are in the state store but none is configured")
+		}
+		data, err = store.Get(ctx, strings.TrimPrefix(src, "state:"))
+	default:
+		return nil, fmt.Errorf("unknown subscription source %q", src)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var subs []subscription
+	if err := json.Unmarshal(data, &subs); err != nil {
+		return nil, fmt.Errorf("cannot decode subscriptions: %w", err)
+	}
+	return subs, nil
+}
+
+func fetchURL(ctx context.Context, target string, timeout time.Duration) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := (&http.Client{Timeout: timeout}).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &statusError{Code: resp.StatusCode, Status: resp.Status}
+	}
+	return io.ReadAll(resp.Body)
+}
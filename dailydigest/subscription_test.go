@@ -0,0 +1,57 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSchedulesFor(t *testing.T) {
+	daily := schedule{CType: TypeDailyAt4P, Hour: 16}
+	weekly := schedule{CType: TypeWeeklyAt9A, Hour: 9}
+	subs := newSubscriptionSet([]subscription{
+		{User: "u-1", Zone: "Europe/Paris", LocalTime: "08:00"},
+		{User: "u-2", Zone: "Europe/Paris", LocalTime: "07:30", Type: TypeWeeklyAt9A},
+		{User: "u-3", Zone: "Asia/Tokyo", LocalTime: "08:00"},
+		{User: "u-3", Zone: "Asia/Tokyo", LocalTime: "10:00", Type: TypeWeeklyAt9A},
+		{Zone: "America/Chicago"},
+		{Tenant: "acme", User: "u-4", Zone: "America/Chicago", LocalTime: "18:00"},
+	})
+	tests := []struct {
+		name   string
+		tenant string
+		zone   string
+		def    schedule
+		want   []schedule
+	}{
+		// u-2 takes the tenant's daily time, u-1 its own.
+		{"daily mixed", "", "Europe/Paris", daily, []schedule{daily, daily.at(8, 0)}},
+		// u-1 only moved the daily digest.
+		{"weekly mixed", "", "Europe/Paris", weekly, []schedule{weekly, weekly.at(7, 30)}},
+		{"every type preferred", "", "Asia/Tokyo", daily, []schedule{daily.at(8, 0)}},
+		{"every type preferred weekly", "", "Asia/Tokyo", weekly, []schedule{weekly.at(10, 0)}},
+		// a daily preference alone must not stop the weekly digest.
+		{"daily preference only", "acme", "America/Chicago", weekly, []schedule{weekly}},
+		{"global default plus tenant preference", "acme", "America/Chicago", daily, []schedule{daily, daily.at(18, 0)}},
+		{"unsubscribed zone", "", "Australia/Sydney", daily, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := subs.schedulesFor(tt.tenant, tt.zone, tt.def)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("schedulesFor(%q, %q, %s) = %+v, want %+v", tt.tenant, tt.zone, tt.def.CType, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchedulesForDailyOnlyZone(t *testing.T) {
+	// every subscriber in the zone prefers a daily time; weekly still fires.
+	subs := newSubscriptionSet([]subscription{
+		{User: "u-1", Zone: "Asia/Kolkata", LocalTime: "08:00"},
+		{User: "u-2", Zone: "Asia/Kolkata", LocalTime: "09:00"},
+	})
+	weekly := schedule{CType: TypeWeeklyAt9A, Hour: 9}
+	if got := subs.schedulesFor("", "Asia/Kolkata", weekly); !reflect.DeepEqual(got, []schedule{weekly}) {
+		t.Fatalf("schedulesFor weekly = %+v, want the tenant's schedule", got)
+	}
+}
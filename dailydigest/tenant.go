@@ -0,0 +1,336 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// defaultTenantID names the tenant built from the environment when no
+// tenants file is configured.
+const defaultTenantID = "default"
+
+// tenant is one workspace the digest is triggered for. Each tenant has its
+// own endpoint and credentials, may switch CTypes off, move their local time,
+// and narrow the catalog down to the zones it cares about.
+type tenant struct {
+	ID       string         `json:"id"`
+	Endpoint string         `json:"endpoint"`
+	Token    string         `json:"token,omitempty"`
+	OAuth    *oauthSettings `json:"oauth,omitempty"`
+	// TLS replaces the DIGEST_TLS_* settings for this tenant's endpoint.
+	TLS *tlsSettings `json:"tls,omitempty"`
+
+	// CTypes lists the digest types enabled for the tenant; empty means all.
+	CTypes []CType `json:"ctypes,omitempty"`
+	// Schedules replaces the built-in schedule of the same CType.
+	Schedules []schedule `json:"schedules,omitempty"`
+	// Zones restricts evaluation to these IANA zones or abbreviation
+	// groups; empty means the whole catalog.
+	Zones []string `json:"zones,omitempty"`
+	// QuietHours are added to the global windows for this tenant.
+	QuietHours []quietWindow `json:"quiet_hours,omitempty"`
+	// HolidayPolicies overrides the global holiday policy per CType.
+	HolidayPolicies map[CType]string `json:"holiday_policies,omitempty"`
+	// Fiscal is the tenant's fiscal calendar, needed by the fiscal digests.
+	Fiscal *fiscalCalendar `json:"fiscal,omitempty"`
+	// WorkWeeks overrides the work week by zone, abbreviation or country.
+	WorkWeeks map[string]workWeekSpec `json:"work_weeks,omitempty"`
+	// Locales overrides the zone's default locale by zone, abbreviation or
+	// country.
+	Locales map[string]string `json:"locales,omitempty"`
+
+	quiet           []quietWindow
+	holidays        *holidays
+	workWeeks       workWeekOverrides
+	globalWorkWeeks workWeekOverrides
+	messages        messageCatalog
+	bodyToken       string
+	tokens          *tokenSource
+	client          *deliveryClient
+	zoneSet         map[string]bool
+}
+
+// builtinSchedules are the schedules every tenant starts from.
+var builtinSchedules = []schedule{
+	dailySchedule, weeklySchedule, monthlySchedule, quarterlySchedule, yearlySchedule, fiscalPeriodSchedule, fiscalWeekSchedule,
+}
+
+// loadTenants reads the tenants named by DIGEST_TENANTS, a secret reference
+// to a JSON array of tenants. Without it the lambda serves a single tenant
+// configured from the environment. A tenant that fails to load is reported
+// in the returned error and left out, so it cannot hold up the others. So is
+// a global quiet-hours, holidays, work-week or messages document that fails
+// to load; tenants then run without it.
+func loadTenants(ctx context.Context, c config) ([]*tenant, error) {
+	var errs []error
+	global, err := loadQuietHours(ctx, c)
+	if err != nil {
+		errs = append(errs, err)
+		global = nil
+	}
+	hols, err := loadHolidays(ctx, c)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	weeks, err := loadWorkWeeks(ctx, c)
+	if err != nil {
+		errs = append(errs, err)
+		weeks = nil
+	}
+	messages, err := loadMessages(ctx, c)
+	if err != nil {
+		errs = append(errs, err)
+		messages = builtinMessages
+	}
+	tenants, err := readTenants(ctx, c)
+	for _, tn := range tenants {
+		tn.messages = messages
+		tn.quiet = append(append([]quietWindow(nil), global...), tn.QuietHours...)
+		tn.holidays = hols
+		tn.globalWorkWeeks = weeks
+	}
+	return tenants, errors.Join(append(errs, err)...)
+}
+
+func readTenants(ctx context.Context, c config) ([]*tenant, error) {
+	if c.Tenants == "" {
+		tn := &tenant{ID: defaultTenantID, Endpoint: c.Endpoint, Token: "<TOKEN>", CTypes: c.CTypes}
+		if c.OAuthTokenURL != "" {
+			tn.OAuth = &oauthSettings{
+				TokenURL:     c.OAuthTokenURL,
+				ClientID:     c.OAuthClientID,
+				ClientSecret: c.OAuthClientSecret,
+				Scope:        c.OAuthScope,
+				Audience:     c.OAuthAudience,
+			}
+		}
+		if err := tn.prepare(ctx, c); err != nil {
+			return nil, err
+		}
+		return []*tenant{tn}, nil
+	}
+	data, err := loadSecret(ctx, c.Tenants)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read tenants: %w", err)
+	}
+	var all []*tenant
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, fmt.Errorf("cannot decode tenants: %w", err)
+	}
+	var (
+		tenants []*tenant
+		errs    []error
+		seen    = map[string]bool{}
+	)
+	for i, tn := range all {
+		if tn.ID == "" {
+			errs = append(errs, fmt.Errorf("tenant #%d has no id", i))
+			continue
+		}
+		if seen[tn.ID] {
+			errs = append(errs, fmt.Errorf("tenant %s is defined twice", tn.ID))
+			continue
+		}
+		seen[tn.ID] = true
+		if err := tn.prepare(ctx, c); err != nil {
+			errs = append(errs, fmt.Errorf("tenant %s: %w", tn.ID, err))
+			continue
+		}
+		tenants = append(tenants, tn)
+	}
+	return tenants, errors.Join(errs...)
+}
+
+// prepare validates the tenant and resolves its secrets.
+func (tn *tenant) prepare(ctx context.Context, c config) error {
+	if u, err := url.Parse(tn.Endpoint); err != nil || !u.IsAbs() {
+		return fmt.Errorf("endpoint %q is not an absolute URL", tn.Endpoint)
+	}
+	known := map[CType]bool{}
+	for _, s := range builtinSchedules {
+		known[s.CType] = true
+	}
+	for _, ct := range tn.CTypes {
+		if !known[ct] {
+			return fmt.Errorf("unknown ctype %q", ct)
+		}
+	}
+	for _, s := range tn.Schedules {
+		if !known[s.CType] {
+			return fmt.Errorf("schedule for unknown ctype %q", s.CType)
+		}
+		if s.Hour < 0 || s.Hour > 23 || s.Minute < 0 || s.Minute > 59 {
+			return fmt.Errorf("schedule for %s has invalid time %02d:%02d", s.CType, s.Hour, s.Minute)
+		}
+		if s.FirstWorkday && s.Weekday != nil {
+			return fmt.Errorf("schedule for %s sets both weekday and first_workday", s.CType)
+		}
+		if err := checkBusinessDays(s); err != nil {
+			return err
+		}
+		if s.Solar != nil {
+			if err := s.Solar.prepare(); err != nil {
+				return fmt.Errorf("schedule for %s: %w", s.CType, err)
+			}
+		}
+	}
+	for i := range tn.Schedules {
+		if err := tn.Schedules[i].preparePeriod(); err != nil {
+			return err
+		}
+	}
+	if tn.Fiscal != nil {
+		if err := tn.Fiscal.prepare(); err != nil {
+			return err
+		}
+	} else {
+		for _, ct := range tn.CTypes {
+			if ct == TypeFiscalPeriodAt9A || ct == TypeFiscalWeekAt9A {
+				return fmt.Errorf("%s needs a fiscal calendar", ct)
+			}
+		}
+	}
+	if tn.OAuth != nil {
+		if tn.OAuth.TokenURL == "" || tn.OAuth.ClientID == "" || tn.OAuth.ClientSecret == "" {
+			return fmt.Errorf("oauth needs token_url, client_id and client_secret")
+		}
+		tn.tokens = tokenSourceFor(c, *tn.OAuth)
+	} else if tn.Token != "" {
+		token, err := loadSecret(ctx, tn.Token)
+		if err != nil {
+			return fmt.Errorf("cannot read token: %w", err)
+		}
+		tn.bodyToken = string(token)
+	}
+	if tn.TLS != nil {
+		if (tn.TLS.Cert == "") != (tn.TLS.Key == "") {
+			return fmt.Errorf("tls cert and key must be set together")
+		}
+		tn.client = tenantClient(c, tn.ID, *tn.TLS)
+	}
+	if err := prepareQuietWindows(tn.QuietHours); err != nil {
+		return err
+	}
+	if err := checkHolidayPolicies(tn.HolidayPolicies); err != nil {
+		return err
+	}
+	weeks, err := parseWorkWeeks(tn.WorkWeeks)
+	if err != nil {
+		return err
+	}
+	tn.workWeeks = weeks
+	if err := checkLocales(tn.Locales); err != nil {
+		return err
+	}
+	if len(tn.Zones) > 0 {
+		tn.zoneSet = map[string]bool{}
+		for _, z := range tn.Zones {
+			if !inCatalog(z) {
+				return fmt.Errorf("zone %q is neither a catalog zone nor an abbreviation group", z)
+			}
+			tn.zoneSet[z] = true
+		}
+	}
+	return nil
+}
+
+// inCatalog reports whether name is an abbreviation group of the timezones
+// catalog or one of the zones listed under it.
+func inCatalog(name string) bool {
+	if _, ok := timezones[name]; ok {
+		return true
+	}
+	for _, zones := range timezones {
+		for _, tz := range zones {
+			if tz == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// delivery returns the client for the tenant's endpoint: its own when it has
+// TLS settings, the shared one otherwise.
+func (tn *tenant) delivery() *deliveryClient {
+	if tn.client != nil {
+		return tn.client
+	}
+	return httpClient
+}
+
+// schedules returns the tenant's enabled schedules, built-ins first with any
+// overrides applied.
+func (tn *tenant) schedules() []schedule {
+	var out []schedule
+	for _, s := range builtinSchedules {
+		if s, ok := tn.schedule(s.CType); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// schedule returns the tenant's schedule for cType, and false when the tenant
+// has that CType switched off.
+func (tn *tenant) schedule(cType CType) (schedule, bool) {
+	if len(tn.CTypes) > 0 {
+		enabled := false
+		for _, ct := range tn.CTypes {
+			enabled = enabled || ct == cType
+		}
+		if !enabled {
+			return schedule{}, false
+		}
+	} else if isPeriodType(cType) {
+		return schedule{}, false
+	}
+	for _, s := range tn.Schedules {
+		if s.CType == cType {
+			return s, true
+		}
+	}
+	for _, s := range builtinSchedules {
+		if s.CType == cType {
+			return s, true
+		}
+	}
+	return schedule{}, false
+}
+
+// includes reports whether zone, found under the abbreviation group, is in
+// the tenant's zone subset.
+func (tn *tenant) includes(group, zone string) bool {
+	return tn.zoneSet == nil || tn.zoneSet[zone] || tn.zoneSet[group]
+}
+
+// withBodyToken adds the static body token the endpoint expects when
+// requests are not authenticated with OAuth2 bearer tokens. It is added only
+// on the way out, so reports and dead letters never hold it and a redrive
+// sends the current token; any token an older entry still carries is
+// replaced.
+func (tn *tenant) withBodyToken(payload []byte) ([]byte, error) {
+	var body map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &body); err != nil {
+		return nil, fmt.Errorf("cannot add token to payload: %w", err)
+	}
+	delete(body, "token")
+	if !tn.tokens.enabled() && tn.bodyToken != "" {
+		token, _ := json.Marshal(tn.bodyToken)
+		body["token"] = token
+	}
+	return json.Marshal(body)
+}
+
+// tenantByID returns the tenant with id from tenants, or nil.
+func tenantByID(tenants []*tenant, id string) *tenant {
+	for _, tn := range tenants {
+		if tn.ID == id {
+			return tn
+		}
+	}
+	return nil
+}
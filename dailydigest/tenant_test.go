@@ -0,0 +1,34 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTenantPrepareRejectsUnknownNames(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		ctypes []CType
+		zones  []string
+		want   string
+	}{
+		{"whole catalog", nil, nil, ""},
+		{"abbreviation group", nil, []string{"CET"}, ""},
+		{"catalog zone", nil, []string{"Europe/London", "Asia/Kolkata"}, ""},
+		{"misspelt zone", nil, []string{"CET", "Europe/Londn"}, `zone "Europe/Londn"`},
+		{"unknown abbreviation", nil, []string{"XYZT"}, `zone "XYZT"`},
+		{"lowercase abbreviation", nil, []string{"cet"}, `zone "cet"`},
+		{"unknown ctype", []CType{"hourly"}, nil, `unknown ctype "hourly"`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tn := &tenant{ID: "acme", Endpoint: "https://acme.example.com/digest", CTypes: tt.ctypes, Zones: tt.zones}
+			err := tn.prepare(t.Context(), config{})
+			if tt.want == "" && err != nil {
+				t.Errorf("prepare = %v, want no error", err)
+			}
+			if tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)) {
+				t.Errorf("prepare = %v, want it to mention %s", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// deliveryClient hands out the HTTP client used by post. When client
+// certificates or a private CA are configured it re-reads them every
+// TLSReload and swaps in a new transport if the material changed, so a
+// rotated secret takes effect without a cold start.
+type deliveryClient struct {
+	cfg config
+
+	mu       sync.Mutex
+	client   *http.Client
+	material []byte
+	checked  time.Time
+}
+
+// tlsMaterial is the PEM data behind the configured TLS secret references.
+type tlsMaterial struct {
+	cert, key, ca []byte
+}
+
+// bytes concatenates every secret so a change in any of them is noticed.
+func (m tlsMaterial) bytes() []byte {
+	var all []byte
+	for _, b := range [][]byte{m.cert, m.key, m.ca} {
+		all = append(append(all, b...), 0)
+	}
+	return all
+}
+
+var httpClient = &deliveryClient{cfg: cfg}
+
+// tlsSettings lets a tenant bring its own TLS material in place of the
+// DIGEST_TLS_* settings. Cert, Key and CA are secret references.
+type tlsSettings struct {
+	Cert       string `json:"cert,omitempty"`
+	Key        string `json:"key,omitempty"`
+	CA         string `json:"ca,omitempty"`
+	MinVersion string `json:"min_version,omitempty"`
+	ServerName string `json:"server_name,omitempty"`
+}
+
+type tenantClientKey struct {
+	tenant string
+	tls    tlsSettings
+}
+
+// tenantClients live at package level, like httpClient, so a tenant's
+// connections and reload state survive warm invocations.
+var (
+	tenantClientsMu sync.Mutex
+	tenantClients   = map[tenantClientKey]*deliveryClient{}
+)
+
+// tenantClient returns the delivery client for a tenant with its own TLS
+// settings. Changed settings get a new client.
+func tenantClient(c config, id string, s tlsSettings) *deliveryClient {
+	c.TLSCert, c.TLSKey, c.TLSCA = s.Cert, s.Key, s.CA
+	c.TLSMinVersion, c.TLSServerName = s.MinVersion, s.ServerName
+	tenantClientsMu.Lock()
+	defer tenantClientsMu.Unlock()
+	key := tenantClientKey{id, s}
+	d, ok := tenantClients[key]
+	if !ok {
+		d = &deliveryClient{cfg: c}
+		tenantClients[key] = d
+	}
+	return d
+}
+
+func (d *deliveryClient) get(ctx context.Context) (*http.Client, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.client != nil && (!d.cfg.tlsConfigured() || time.Since(d.checked) < d.cfg.TLSReload) {
+		return d.client, nil
+	}
+	material, err := d.loadMaterial(ctx)
+	if err != nil {
+		if d.client != nil {
+			// keep using the last good certificates until the secret is readable again.
+			slog.Warn("cannot reload tls material, keeping previous", "error", err)
+			d.checked = time.Now()
+			return d.client, nil
+		}
+		return nil, err
+	}
+	d.checked = time.Now()
+	if d.client != nil && bytes.Equal(material.bytes(), d.material) {
+		return d.client, nil
+	}
+	tlsCfg, err := d.buildTLS(material)
+	if err != nil {
+		if d.client != nil {
+			// a half-rotated secret, e.g. a new certificate with the old key.
+			slog.Warn("cannot rebuild tls config, keeping previous", "error", err)
+			return d.client, nil
+		}
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsCfg
+	if d.client != nil {
+		slog.Info("tls material changed, reloading delivery client")
+		d.client.CloseIdleConnections()
+	}
+	d.client = &http.Client{Timeout: d.cfg.Timeout, Transport: transport}
+	d.material = material.bytes()
+	return d.client, nil
+}
+
+// loadMaterial reads every configured secret once.
+func (d *deliveryClient) loadMaterial(ctx context.Context) (tlsMaterial, error) {
+	var m tlsMaterial
+	for _, s := range []struct {
+		ref string
+		dst *[]byte
+	}{{d.cfg.TLSCert, &m.cert}, {d.cfg.TLSKey, &m.key}, {d.cfg.TLSCA, &m.ca}} {
+		if s.ref == "" {
+			continue
+		}
+		b, err := loadSecret(ctx, s.ref)
+		if err != nil {
+			return tlsMaterial{}, err
+		}
+		*s.dst = b
+	}
+	return m, nil
+}
+
+func (d *deliveryClient) buildTLS(m tlsMaterial) (*tls.Config, error) {
+	c := d.cfg
+	if !c.tlsConfigured() {
+		return nil, nil
+	}
+	tlsCfg := &tls.Config{ServerName: c.TLSServerName}
+	switch c.TLSMinVersion {
+	case "", "1.2":
+		tlsCfg.MinVersion = tls.VersionTLS12
+	case "1.3":
+		tlsCfg.MinVersion = tls.VersionTLS13
+	default:
+		return nil, fmt.Errorf("unsupported tls min version %q", c.TLSMinVersion)
+	}
+	if c.TLSCert != "" || c.TLSKey != "" {
+		cert, err := tls.X509KeyPair(m.cert, m.key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate: %w", err)
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+	if c.TLSCA != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(m.ca) {
+			return nil, fmt.Errorf("no certificates found in ca bundle")
+		}
+		tlsCfg.RootCAs = pool
+	}
+	return tlsCfg, nil
+}
+
+func (c config) tlsConfigured() bool {
+	return c.TLSCert != "" || c.TLSKey != "" || c.TLSCA != "" || c.TLSMinVersion != "" || c.TLSServerName != ""
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeKeyPair writes a fresh self-signed certificate and its key to dir.
+func writeKeyPair(t *testing.T, dir string) (cert, key string) {
+	t.Helper()
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "digest"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &priv.PublicKey, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, key = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writeFile(t, cert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	writeFile(t, key, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+	return cert, key
+}
+
+func writeFile(t *testing.T, name string, data []byte) {
+	t.Helper()
+	if err := os.WriteFile(name, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDeliveryClientReload(t *testing.T) {
+	dir := t.TempDir()
+	cert, key := writeKeyPair(t, dir)
+	// a zero reload interval re-reads the material on every call.
+	d := &deliveryClient{cfg: config{TLSCert: "file:" + cert, TLSKey: "file:" + key, Timeout: time.Second}}
+	first, err := d.get(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := d.get(t.Context()); again != first {
+		t.Error("unchanged material built a new client")
+	}
+
+	// a half-rotated secret: new certificate, old key.
+	good, _ := os.ReadFile(cert)
+	next := t.TempDir()
+	newCert, newKey := writeKeyPair(t, next)
+	rotated, _ := os.ReadFile(newCert)
+	writeFile(t, cert, rotated)
+	if got, err := d.get(t.Context()); err != nil || got != first {
+		t.Errorf("mismatched key pair gave %p, %v, want the previous client", got, err)
+	}
+
+	// a secret that cannot be read.
+	writeFile(t, cert, good)
+	os.Remove(key)
+	if got, err := d.get(t.Context()); err != nil || got != first {
+		t.Errorf("unreadable key gave %p, %v, want the previous client", got, err)
+	}
+
+	newKeyPEM, _ := os.ReadFile(newKey)
+	writeFile(t, cert, rotated)
+	writeFile(t, key, newKeyPEM)
+	second, err := d.get(t.Context())
+	if err != nil || second == first {
+		t.Fatalf("rotated material gave %p, %v, want a new client", second, err)
+	}
+	if again, _ := d.get(t.Context()); again != second {
+		t.Error("rotated client was not kept")
+	}
+
+	fresh := &deliveryClient{cfg: config{TLSCert: "file:" + cert, TLSKey: "file:" + filepath.Join(dir, "missing.pem")}}
+	if _, err := fresh.get(t.Context()); err == nil {
+		t.Error("a client without previous material built without its key")
+	}
+}
+
+func TestTenantTLSClients(t *testing.T) {
+	dir := t.TempDir()
+	cert, key := writeKeyPair(t, dir)
+	tenants := filepath.Join(dir, "tenants.json")
+	writeFile(t, tenants, []byte(`[
+		{"id": "acme", "endpoint": "https://acme.example.com/digest"},
+		{"id": "globex", "endpoint": "https://globex.example.com/digest",
+		 "tls": {"cert": "file:`+cert+`", "key": "file:`+key+`", "server_name": "digest.globex.internal"}},
+		{"id": "initech", "endpoint": "https://initech.example.com/digest", "tls": {"cert": "file:`+cert+`"}}
+	]`))
+	useConfig(t, func(c *config) { c.Tenants = "file:" + tenants })
+	loaded, err := loadTenants(t.Context(), cfg)
+	if err == nil {
+		t.Error("tenant with a certificate but no key loaded")
+	}
+	if len(loaded) != 2 {
+		t.Fatalf("loaded %d tenants, want acme and globex", len(loaded))
+	}
+	acme, globex := loaded[0], loaded[1]
+	if acme.delivery() != httpClient {
+		t.Error("tenant without tls settings does not share the client")
+	}
+	if globex.delivery() == httpClient || globex.delivery().cfg.TLSServerName != "digest.globex.internal" {
+		t.Fatal("tenant tls settings were not applied to its own client")
+	}
+	client, err := globex.delivery().get(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr, ok := client.Transport.(*http.Transport); !ok || tr.TLSClientConfig.ServerName != "digest.globex.internal" || len(tr.TLSClientConfig.Certificates) != 1 {
+		t.Error("tenant client does not carry its tls settings")
+	}
+
+	reloaded, _ := loadTenants(t.Context(), cfg)
+	if reloaded[1].delivery() != globex.delivery() {
+		t.Error("reloading the same tenant built a new client")
+	}
+	if detail, err := checkSecrets(t.Context(), loaded); err != nil || detail != "globex tls" {
+		t.Errorf("checkSecrets = %q, %v", detail, err)
+	}
+}
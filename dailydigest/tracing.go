@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer is a no-op until setupTracing installs an exporting provider.
+var tracer = otel.Tracer("github.com/sankarvj/snippets/dailydigest")
+
+// tracerProvider is non-nil only when spans are exported; it is flushed at
+// the end of every invocation because a frozen Lambda cannot export later.
+var tracerProvider *sdktrace.TracerProvider
+
+func init() {
+	// traceparent is injected into digest requests even with the no-op
+	// provider, it simply carries nothing then.
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	if err := setupTracing(context.Background(), cfg); err != nil {
+		baseLogger.Error("cannot set up tracing, spans will not be exported", "error", err)
+	}
+}
+
+// setupTracing exports spans over OTLP/HTTP when DIGEST_OTLP_ENDPOINT is set,
+// e.g. http://localhost:4318 for a local collector or the ADOT layer.
+func setupTracing(ctx context.Context, c config) error {
+	if c.OTLPEndpoint == "" {
+		return nil
+	}
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(c.OTLPEndpoint))
+	if err != nil {
+		return err
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", c.ServiceName),
+	))
+	if err != nil {
+		return err
+	}
+	tracerProvider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(tracerProvider)
+	tracer = tracerProvider.Tracer("github.com/sankarvj/snippets/dailydigest")
+	return nil
+}
+
+// flushTraces exports every span ended so far.
+func flushTraces(ctx context.Context) {
+	if tracerProvider == nil {
+		return
+	}
+	if err := tracerProvider.ForceFlush(ctx); err != nil {
+		slog.Warn("cannot flush spans", "error", err)
+	}
+}
+
+// endSpan records err on span, if any, and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,198 @@
+package tzabbr
+
+import "time"
+
+// meaning is one reading of an abbreviation in one country.
+type meaning struct {
+	abbr     string
+	name     string
+	offset   time.Duration
+	daylight bool
+	country  string
+	zones    []string
+}
+
+func utc(hours, minutes int) time.Duration {
+	if hours < 0 {
+		minutes = -minutes
+	}
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute
+}
+
+// table lists the abbreviations in common use, with the zones that observe
+// each meaning. Zones stay listed under meanings they only observe part of
+// the year; Hint.At tells them apart.
+var table = []meaning{
+	{"IST", "India Standard Time", utc(5, 30), false, "IN", []string{"Asia/Kolkata", "Asia/Calcutta"}},
+	{"IST", "Irish Standard Time", utc(1, 0), true, "IE", []string{"Europe/Dublin", "Eire"}},
+	{"IST", "Israel Standard Time", utc(2, 0), false, "IL", []string{"Asia/Jerusalem", "Asia/Tel_Aviv", "Israel"}},
+	{"IDT", "Israel Daylight Time", utc(3, 0), true, "IL", []string{"Asia/Jerusalem", "Asia/Tel_Aviv", "Israel"}},
+	{"SLST", "Sri Lanka Standard Time", utc(5, 30), false, "LK", []string{"Asia/Colombo"}},
+
+	{"CST", "Central Standard Time", utc(-6, 0), false, "US", []string{"America/Chicago", "America/Indiana/Knox", "America/Indiana/Tell_City", "America/Menominee", "America/North_Dakota/Beulah", "America/North_Dakota/Center", "America/North_Dakota/New_Salem", "US/Central", "CST6CDT"}},
+	{"CST", "Central Standard Time", utc(-6, 0), false, "CA", []string{"America/Winnipeg", "America/Regina", "America/Swift_Current", "America/Rankin_Inlet", "America/Resolute", "Canada/Central", "Canada/Saskatchewan"}},
+	{"CST", "Central Standard Time", utc(-6, 0), false, "MX", []string{"America/Mexico_City", "America/Monterrey", "America/Merida", "America/Matamoros", "America/Bahia_Banderas", "America/Chihuahua", "Mexico/General"}},
+	{"CST", "Central Standard Time", utc(-6, 0), false, "BZ", []string{"America/Belize"}},
+	{"CST", "Central Standard Time", utc(-6, 0), false, "CR", []string{"America/Costa_Rica"}},
+	{"CST", "Central Standard Time", utc(-6, 0), false, "SV", []string{"America/El_Salvador"}},
+	{"CST", "Central Standard Time", utc(-6, 0), false, "GT", []string{"America/Guatemala"}},
+	{"CST", "Central Standard Time", utc(-6, 0), false, "HN", []string{"America/Tegucigalpa"}},
+	{"CST", "Central Standard Time", utc(-6, 0), false, "NI", []string{"America/Managua"}},
+	{"CST", "China Standard Time", utc(8, 0), false, "CN", []string{"Asia/Shanghai", "Asia/Chongqing", "Asia/Chungking", "Asia/Harbin", "PRC"}},
+	{"CST", "China Standard Time", utc(8, 0), false, "MO", []string{"Asia/Macau", "Asia/Macao"}},
+	{"CST", "Taiwan Standard Time", utc(8, 0), false, "TW", []string{"Asia/Taipei", "ROC"}},
+	{"CST", "Cuba Standard Time", utc(-5, 0), false, "CU", []string{"America/Havana", "Cuba"}},
+	{"CDT", "Central Daylight Time", utc(-5, 0), true, "US", []string{"America/Chicago", "America/Indiana/Knox", "America/Indiana/Tell_City", "America/Menominee", "America/North_Dakota/Beulah", "America/North_Dakota/Center", "America/North_Dakota/New_Salem", "US/Central", "CST6CDT"}},
+	{"CDT", "Central Daylight Time", utc(-5, 0), true, "CA", []string{"America/Winnipeg", "America/Rankin_Inlet", "America/Resolute", "Canada/Central"}},
+	{"CDT", "Central Daylight Time", utc(-5, 0), true, "MX", []string{"America/Matamoros"}},
+	{"CDT", "Cuba Daylight Time", utc(-4, 0), true, "CU", []string{"America/Havana", "Cuba"}},
+
+	{"BST", "British Summer Time", utc(1, 0), true, "GB", []string{"Europe/London", "Europe/Belfast", "GB", "GB-Eire"}},
+	{"BST", "British Summer Time", utc(1, 0), true, "GG", []string{"Europe/Guernsey"}},
+	{"BST", "British Summer Time", utc(1, 0), true, "IM", []string{"Europe/Isle_of_Man"}},
+	{"BST", "British Summer Time", utc(1, 0), true, "JE", []string{"Europe/Jersey"}},
+	{"BST", "Bangladesh Standard Time", utc(6, 0), false, "BD", []string{"Asia/Dhaka", "Asia/Dacca"}},
+	{"BST", "Bougainville Standard Time", utc(11, 0), false, "PG", []string{"Pacific/Bougainville"}},
+
+	{"AST", "Atlantic Standard Time", utc(-4, 0), false, "CA", []string{"America/Halifax", "America/Glace_Bay", "America/Moncton", "America/Goose_Bay", "America/Blanc-Sablon", "Canada/Atlantic"}},
+	{"AST", "Atlantic Standard Time", utc(-4, 0), false, "BM", []string{"Atlantic/Bermuda"}},
+	{"AST", "Atlantic Standard Time", utc(-4, 0), false, "GL", []string{"America/Thule"}},
+	{"AST", "Atlantic Standard Time", utc(-4, 0), false, "PR", []string{"America/Puerto_Rico"}},
+	{"AST", "Atlantic Standard Time", utc(-4, 0), false, "DO", []string{"America/Santo_Domingo"}},
+	{"AST", "Atlantic Standard Time", utc(-4, 0), false, "VI", []string{"America/St_Thomas", "America/Virgin"}},
+	{"AST", "Atlantic Standard Time", utc(-4, 0), false, "VG", []string{"America/Tortola"}},
+	{"AST", "Atlantic Standard Time", utc(-4, 0), false, "AI", []string{"America/Anguilla"}},
+	{"AST", "Atlantic Standard Time", utc(-4, 0), false, "AG", []string{"America/Antigua"}},
+	{"AST", "Atlantic Standard Time", utc(-4, 0), false, "AW", []string{"America/Aruba"}},
+	{"AST", "Atlantic Standard Time", utc(-4, 0), false, "BB", []string{"America/Barbados"}},
+	{"AST", "Atlantic Standard Time", utc(-4, 0), false, "BL", []string{"America/St_Barthelemy"}},
+	{"AST", "Atlantic Standard Time", utc(-4, 0), false, "BQ", []string{"America/Kralendijk"}},
+	{"AST", "Atlantic Standard Time", utc(-4, 0), false, "CW", []string{"America/Curacao"}},
+	{"AST", "Atlantic Standard Time", utc(-4, 0), false, "DM", []string{"America/Dominica"}},
+	{"AST", "Atlantic Standard Time", utc(-4, 0), false, "GD", []string{"America/Grenada"}},
+	{"AST", "Atlantic Standard Time", utc(-4, 0), false, "GP", []string{"America/Guadeloupe"}},
+	{"AST", "Atlantic Standard Time", utc(-4, 0), false, "KN", []string{"America/St_Kitts"}},
+	{"AST", "Atlantic Standard Time", utc(-4, 0), false, "LC", []string{"America/St_Lucia"}},
+	{"AST", "Atlantic Standard Time", utc(-4, 0), false, "MF", []string{"America/Marigot"}},
+	{"AST", "Atlantic Standard Time", utc(-4, 0), false, "MQ", []string{"America/Martinique"}},
+	{"AST", "Atlantic Standard Time", utc(-4, 0), false, "MS", []string{"America/Montserrat"}},
+	{"AST", "Atlantic Standard Time", utc(-4, 0), false, "SX", []string{"America/Lower_Princes"}},
+	{"AST", "Atlantic Standard Time", utc(-4, 0), false, "TT", []string{"America/Port_of_Spain"}},
+	{"AST", "Atlantic Standard Time", utc(-4, 0), false, "VC", []string{"America/St_Vincent"}},
+	{"AST", "Arabia Standard Time", utc(3, 0), false, "SA", []string{"Asia/Riyadh"}},
+	{"AST", "Arabia Standard Time", utc(3, 0), false, "KW", []string{"Asia/Kuwait"}},
+	{"AST", "Arabia Standard Time", utc(3, 0), false, "BH", []string{"Asia/Bahrain"}},
+	{"AST", "Arabia Standard Time", utc(3, 0), false, "QA", []string{"Asia/Qatar"}},
+	{"AST", "Arabia Standard Time", utc(3, 0), false, "IQ", []string{"Asia/Baghdad"}},
+	{"AST", "Arabia Standard Time", utc(3, 0), false, "YE", []string{"Asia/Aden"}},
+	{"ADT", "Atlantic Daylight Time", utc(-3, 0), true, "CA", []string{"America/Halifax", "America/Glace_Bay", "America/Moncton", "America/Goose_Bay", "Canada/Atlantic"}},
+	{"ADT", "Atlantic Daylight Time", utc(-3, 0), true, "BM", []string{"Atlantic/Bermuda"}},
+	{"ADT", "Atlantic Daylight Time", utc(-3, 0), true, "GL", []string{"America/Thule"}},
+
+	{"EST", "Eastern Standard Time", utc(-5, 0), false, "US", []string{"America/New_York", "America/Detroit", "America/Indiana/Indianapolis", "America/Indiana/Marengo", "America/Indiana/Petersburg", "America/Indiana/Vevay", "America/Indiana/Vincennes", "America/Indiana/Winamac", "America/Kentucky/Louisville", "America/Kentucky/Monticello", "US/Eastern", "EST5EDT"}},
+	{"EST", "Eastern Standard Time", utc(-5, 0), false, "CA", []string{"America/Toronto", "America/Iqaluit", "America/Atikokan", "Canada/Eastern"}},
+	{"EST", "Eastern Standard Time", utc(-5, 0), false, "MX", []string{"America/Cancun"}},
+	{"EST", "Eastern Standard Time", utc(-5, 0), false, "JM", []string{"America/Jamaica", "Jamaica"}},
+	{"EST", "Eastern Standard Time", utc(-5, 0), false, "PA", []string{"America/Panama"}},
+	{"EST", "Eastern Standard Time", utc(-5, 0), false, "KY", []string{"America/Cayman"}},
+	{"EST", "Eastern Standard Time", utc(-5, 0), false, "BS", []string{"America/Nassau"}},
+	{"EST", "Eastern Standard Time", utc(-5, 0), false, "HT", []string{"America/Port-au-Prince"}},
+	{"EST", "Eastern Standard Time", utc(-5, 0), false, "TC", []string{"America/Grand_Turk"}},
+	{"EDT", "Eastern Daylight Time", utc(-4, 0), true, "US", []string{"America/New_York", "America/Detroit", "America/Indiana/Indianapolis", "America/Indiana/Marengo", "America/Indiana/Petersburg", "America/Indiana/Vevay", "America/Indiana/Vincennes", "America/Indiana/Winamac", "America/Kentucky/Louisville", "America/Kentucky/Monticello", "US/Eastern", "EST5EDT"}},
+	{"EDT", "Eastern Daylight Time", utc(-4, 0), true, "CA", []string{"America/Toronto", "America/Iqaluit", "Canada/Eastern"}},
+	{"EDT", "Eastern Daylight Time", utc(-4, 0), true, "BS", []string{"America/Nassau"}},
+	{"EDT", "Eastern Daylight Time", utc(-4, 0), true, "HT", []string{"America/Port-au-Prince"}},
+	{"EDT", "Eastern Daylight Time", utc(-4, 0), true, "TC", []string{"America/Grand_Turk"}},
+	{"MST", "Mountain Standard Time", utc(-7, 0), false, "US", []string{"America/Denver", "America/Boise", "America/Phoenix", "US/Mountain", "US/Arizona", "MST7MDT"}},
+	{"MST", "Mountain Standard Time", utc(-7, 0), false, "CA", []string{"America/Edmonton", "America/Cambridge_Bay", "America/Inuvik", "America/Creston", "America/Dawson_Creek", "America/Fort_Nelson", "Canada/Mountain"}},
+	{"MST", "Mountain Standard Time", utc(-7, 0), false, "MX", []string{"America/Hermosillo", "America/Mazatlan", "America/Ciudad_Juarez"}},
+	{"MDT", "Mountain Daylight Time", utc(-6, 0), true, "US", []string{"America/Denver", "America/Boise", "US/Mountain", "MST7MDT"}},
+	{"MDT", "Mountain Daylight Time", utc(-6, 0), true, "CA", []string{"America/Edmonton", "America/Cambridge_Bay", "America/Inuvik", "Canada/Mountain"}},
+	{"MDT", "Mountain Daylight Time", utc(-6, 0), true, "MX", []string{"America/Ciudad_Juarez"}},
+	{"PST", "Pacific Standard Time", utc(-8, 0), false, "US", []string{"America/Los_Angeles", "US/Pacific", "PST8PDT"}},
+	{"PST", "Pacific Standard Time", utc(-8, 0), false, "CA", []string{"America/Vancouver", "Canada/Pacific"}},
+	{"PST", "Pacific Standard Time", utc(-8, 0), false, "MX", []string{"America/Tijuana", "Mexico/BajaNorte"}},
+	{"PST", "Philippine Standard Time", utc(8, 0), false, "PH", []string{"Asia/Manila"}},
+	{"PDT", "Pacific Daylight Time", utc(-7, 0), true, "US", []string{"America/Los_Angeles", "US/Pacific", "PST8PDT"}},
+	{"PDT", "Pacific Daylight Time", utc(-7, 0), true, "CA", []string{"America/Vancouver", "Canada/Pacific"}},
+	{"PDT", "Pacific Daylight Time", utc(-7, 0), true, "MX", []string{"America/Tijuana", "Mexico/BajaNorte"}},
+	{"AKST", "Alaska Standard Time", utc(-9, 0), false, "US", []string{"America/Anchorage", "America/Juneau", "America/Sitka", "America/Nome", "America/Yakutat", "America/Metlakatla", "US/Alaska"}},
+	{"AKDT", "Alaska Daylight Time", utc(-8, 0), true, "US", []string{"America/Anchorage", "America/Juneau", "America/Sitka", "America/Nome", "America/Yakutat", "America/Metlakatla", "US/Alaska"}},
+	{"HST", "Hawaii-Aleutian Standard Time", utc(-10, 0), false, "US", []string{"Pacific/Honolulu", "America/Adak", "US/Hawaii", "US/Aleutian"}},
+	{"NST", "Newfoundland Standard Time", utc(-3, 30), false, "CA", []string{"America/St_Johns", "Canada/Newfoundland"}},
+	{"NDT", "Newfoundland Daylight Time", utc(-2, 30), true, "CA", []string{"America/St_Johns", "Canada/Newfoundland"}},
+
+	{"GMT", "Greenwich Mean Time", 0, false, "GB", []string{"Europe/London", "GB"}},
+	{"GMT", "Greenwich Mean Time", 0, false, "IE", []string{"Europe/Dublin", "Eire"}},
+	{"GMT", "Greenwich Mean Time", 0, false, "", []string{"Etc/GMT", "Etc/Greenwich", "GMT"}},
+	{"UTC", "Coordinated Universal Time", 0, false, "", []string{"Etc/UTC", "Etc/UCT", "UTC", "UCT", "Zulu"}},
+	{"WET", "Western European Time", 0, false, "PT", []string{"Europe/Lisbon", "Atlantic/Madeira", "Portugal"}},
+	{"WET", "Western European Time", 0, false, "ES", []string{"Atlantic/Canary"}},
+	{"WET", "Western European Time", 0, false, "FO", []string{"Atlantic/Faroe", "Atlantic/Faeroe"}},
+	{"WEST", "Western European Summer Time", utc(1, 0), true, "PT", []string{"Europe/Lisbon", "Atlantic/Madeira", "Portugal"}},
+	{"WEST", "Western European Summer Time", utc(1, 0), true, "ES", []string{"Atlantic/Canary"}},
+	{"WEST", "Western European Summer Time", utc(1, 0), true, "FO", []string{"Atlantic/Faroe", "Atlantic/Faeroe"}},
+	{"CET", "Central European Time", utc(1, 0), false, "DE", []string{"Europe/Berlin"}},
+	{"CET", "Central European Time", utc(1, 0), false, "FR", []string{"Europe/Paris"}},
+	{"CET", "Central European Time", utc(1, 0), false, "NL", []string{"Europe/Amsterdam"}},
+	{"CET", "Central European Time", utc(1, 0), false, "BE", []string{"Europe/Brussels"}},
+	{"CET", "Central European Time", utc(1, 0), false, "ES", []string{"Europe/Madrid"}},
+	{"CET", "Central European Time", utc(1, 0), false, "IT", []string{"Europe/Rome"}},
+	{"CET", "Central European Time", utc(1, 0), false, "CH", []string{"Europe/Zurich"}},
+	{"CET", "Central European Time", utc(1, 0), false, "AT", []string{"Europe/Vienna"}},
+	{"CET", "Central European Time", utc(1, 0), false, "PL", []string{"Europe/Warsaw", "Poland"}},
+	{"CET", "Central European Time", utc(1, 0), false, "CZ", []string{"Europe/Prague"}},
+	{"CET", "Central European Time", utc(1, 0), false, "SE", []string{"Europe/Stockholm"}},
+	{"CET", "Central European Time", utc(1, 0), false, "NO", []string{"Europe/Oslo"}},
+	{"CET", "Central European Time", utc(1, 0), false, "DK", []string{"Europe/Copenhagen"}},
+	{"CET", "Central European Time", utc(1, 0), false, "HU", []string{"Europe/Budapest"}},
+	{"CET", "Central European Time", utc(1, 0), false, "", []string{"CET"}},
+	{"CEST", "Central European Summer Time", utc(2, 0), true, "DE", []string{"Europe/Berlin"}},
+	{"CEST", "Central European Summer Time", utc(2, 0), true, "FR", []string{"Europe/Paris"}},
+	{"CEST", "Central European Summer Time", utc(2, 0), true, "NL", []string{"Europe/Amsterdam"}},
+	{"CEST", "Central European Summer Time", utc(2, 0), true, "BE", []string{"Europe/Brussels"}},
+	{"CEST", "Central European Summer Time", utc(2, 0), true, "ES", []string{"Europe/Madrid"}},
+	{"CEST", "Central European Summer Time", utc(2, 0), true, "IT", []string{"Europe/Rome"}},
+	{"CEST", "Central European Summer Time", utc(2, 0), true, "CH", []string{"Europe/Zurich"}},
+	{"CEST", "Central European Summer Time", utc(2, 0), true, "AT", []string{"Europe/Vienna"}},
+	{"CEST", "Central European Summer Time", utc(2, 0), true, "PL", []string{"Europe/Warsaw", "Poland"}},
+	{"CEST", "Central European Summer Time", utc(2, 0), true, "CZ", []string{"Europe/Prague"}},
+	{"CEST", "Central European Summer Time", utc(2, 0), true, "SE", []string{"Europe/Stockholm"}},
+	{"CEST", "Central European Summer Time", utc(2, 0), true, "NO", []string{"Europe/Oslo"}},
+	{"CEST", "Central European Summer Time", utc(2, 0), true, "DK", []string{"Europe/Copenhagen"}},
+	{"CEST", "Central European Summer Time", utc(2, 0), true, "HU", []string{"Europe/Budapest"}},
+	{"EET", "Eastern European Time", utc(2, 0), false, "GR", []string{"Europe/Athens"}},
+	{"EET", "Eastern European Time", utc(2, 0), false, "FI", []string{"Europe/Helsinki"}},
+	{"EET", "Eastern European Time", utc(2, 0), false, "RO", []string{"Europe/Bucharest"}},
+	{"EET", "Eastern European Time", utc(2, 0), false, "BG", []string{"Europe/Sofia"}},
+	{"EET", "Eastern European Time", utc(2, 0), false, "UA", []string{"Europe/Kyiv", "Europe/Kiev"}},
+	{"EET", "Eastern European Time", utc(2, 0), false, "EG", []string{"Africa/Cairo", "Egypt"}},
+	{"EET", "Eastern European Time", utc(2, 0), false, "LY", []string{"Africa/Tripoli", "Libya"}},
+	{"EET", "Eastern European Time", utc(2, 0), false, "", []string{"EET"}},
+	{"EEST", "Eastern European Summer Time", utc(3, 0), true, "GR", []string{"Europe/Athens"}},
+	{"EEST", "Eastern European Summer Time", utc(3, 0), true, "FI", []string{"Europe/Helsinki"}},
+	{"EEST", "Eastern European Summer Time", utc(3, 0), true, "RO", []string{"Europe/Bucharest"}},
+	{"EEST", "Eastern European Summer Time", utc(3, 0), true, "BG", []string{"Europe/Sofia"}},
+	{"EEST", "Eastern European Summer Time", utc(3, 0), true, "UA", []string{"Europe/Kyiv", "Europe/Kiev"}},
+	{"EEST", "Eastern European Summer Time", utc(3, 0), true, "EG", []string{"Africa/Cairo", "Egypt"}},
+	{"MSK", "Moscow Standard Time", utc(3, 0), false, "RU", []string{"Europe/Moscow", "W-SU"}},
+
+	{"WAT", "West Africa Time", utc(1, 0), false, "NG", []string{"Africa/Lagos"}},
+	{"CAT", "Central Africa Time", utc(2, 0), false, "MZ", []string{"Africa/Maputo"}},
+	{"EAT", "East Africa Time", utc(3, 0), false, "KE", []string{"Africa/Nairobi"}},
+	{"SAST", "South Africa Standard Time", utc(2, 0), false, "ZA", []string{"Africa/Johannesburg"}},
+	{"PKT", "Pakistan Standard Time", utc(5, 0), false, "PK", []string{"Asia/Karachi"}},
+	{"HKT", "Hong Kong Time", utc(8, 0), false, "HK", []string{"Asia/Hong_Kong", "Hongkong"}},
+	{"SGT", "Singapore Time", utc(8, 0), false, "SG", []string{"Asia/Singapore", "Singapore"}},
+	{"WIB", "Western Indonesia Time", utc(7, 0), false, "ID", []string{"Asia/Jakarta", "Asia/Pontianak"}},
+	{"JST", "Japan Standard Time", utc(9, 0), false, "JP", []string{"Asia/Tokyo", "Japan"}},
+	{"KST", "Korea Standard Time", utc(9, 0), false, "KR", []string{"Asia/Seoul", "ROK"}},
+	{"KST", "Korea Standard Time", utc(9, 0), false, "KP", []string{"Asia/Pyongyang"}},
+	{"AWST", "Australian Western Standard Time", utc(8, 0), false, "AU", []string{"Australia/Perth", "Australia/West"}},
+	{"ACST", "Australian Central Standard Time", utc(9, 30), false, "AU", []string{"Australia/Adelaide", "Australia/Darwin", "Australia/Broken_Hill", "Australia/South", "Australia/North"}},
+	{"ACDT", "Australian Central Daylight Time", utc(10, 30), true, "AU", []string{"Australia/Adelaide", "Australia/Broken_Hill", "Australia/South"}},
+	{"AEST", "Australian Eastern Standard Time", utc(10, 0), false, "AU", []string{"Australia/Sydney", "Australia/Melbourne", "Australia/Brisbane", "Australia/Hobart", "Australia/Lindeman", "Australia/ACT", "Australia/NSW", "Australia/Victoria", "Australia/Queensland", "Australia/Tasmania"}},
+	{"AEDT", "Australian Eastern Daylight Time", utc(11, 0), true, "AU", []string{"Australia/Sydney", "Australia/Melbourne", "Australia/Hobart", "Australia/ACT", "Australia/NSW", "Australia/Victoria", "Australia/Tasmania"}},
+	{"NZST", "New Zealand Standard Time", utc(12, 0), false, "NZ", []string{"Pacific/Auckland", "NZ"}},
+	{"NZDT", "New Zealand Daylight Time", utc(13, 0), true, "NZ", []string{"Pacific/Auckland", "NZ"}},
+}
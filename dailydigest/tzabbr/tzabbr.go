@@ -0,0 +1,142 @@
+// Package tzabbr resolves time zone abbreviations such as "IST" or "CST" to
+// the IANA zones they may stand for.
+//
+// Abbreviations are not unique: "IST" is India, Irish or Israel Standard Time
+// and "CST" is US Central, China or Cuba Standard Time. Resolve returns every
+// meaning with its zones and UTC offset and says when more than one is left,
+// so a caller interpreting user input can ask instead of guessing. A region
+// hint or an instant narrows the candidates down.
+package tzabbr
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+	// observes needs the tz database even where the runtime has none.
+	_ "time/tzdata"
+)
+
+// ErrUnknown is returned for an abbreviation that is not in the table.
+var ErrUnknown = errors.New("tzabbr: unknown abbreviation")
+
+// Candidate is one IANA zone an abbreviation may refer to.
+type Candidate struct {
+	Zone    string
+	Country string
+	// Name is the meaning of the abbreviation, e.g. "India Standard Time".
+	Name string
+	// Offset is the UTC offset the abbreviation stands for, which is the
+	// zone's offset only while it observes that meaning.
+	Offset time.Duration
+	// Daylight marks a daylight saving time meaning, such as BST.
+	Daylight bool
+}
+
+// Hint narrows a resolution down. Region is an ISO 3166 country code, e.g.
+// "IE", or an IANA area such as "Europe" or "America". When At is set, only
+// zones that observe the meaning at that instant are kept, so "BST" in
+// January matches nothing in Europe/London.
+type Hint struct {
+	Region string
+	At     time.Time
+}
+
+// Result lists the candidates for an abbreviation. Meanings are the distinct
+// names among them; Ambiguous is set when there is more than one. A Result
+// without candidates means the hint ruled every meaning out.
+type Result struct {
+	Abbr       string
+	Candidates []Candidate
+	Meanings   []string
+	Ambiguous  bool
+}
+
+// Offsets returns the distinct UTC offsets among the candidates, in
+// ascending order. An ambiguous result with a single offset still denotes a
+// single instant for a given wall clock time.
+func (r Result) Offsets() []time.Duration {
+	seen := map[time.Duration]bool{}
+	var out []time.Duration
+	for _, c := range r.Candidates {
+		if !seen[c.Offset] {
+			seen[c.Offset] = true
+			out = append(out, c.Offset)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
+
+// Resolve returns the zones abbr may refer to, matched case-insensitively.
+func Resolve(abbr string, hint Hint) (Result, error) {
+	abbr = strings.ToUpper(strings.TrimSpace(abbr))
+	ms, ok := byAbbr[abbr]
+	if !ok {
+		return Result{}, ErrUnknown
+	}
+	r := Result{Abbr: abbr}
+	seen := map[string]bool{}
+	for _, m := range ms {
+		for _, zone := range m.zones {
+			if !inRegion(hint.Region, m.country, zone) {
+				continue
+			}
+			if !hint.At.IsZero() && !observes(zone, m.offset, hint.At) {
+				continue
+			}
+			r.Candidates = append(r.Candidates, Candidate{
+				Zone:     zone,
+				Country:  m.country,
+				Name:     m.name,
+				Offset:   m.offset,
+				Daylight: m.daylight,
+			})
+			if !seen[m.name] {
+				seen[m.name] = true
+				r.Meanings = append(r.Meanings, m.name)
+			}
+		}
+	}
+	r.Ambiguous = len(r.Meanings) > 1
+	return r, nil
+}
+
+// Abbreviations returns every abbreviation Resolve knows, sorted.
+func Abbreviations() []string {
+	out := make([]string, 0, len(byAbbr))
+	for abbr := range byAbbr {
+		out = append(out, abbr)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func inRegion(region, country, zone string) bool {
+	switch {
+	case region == "":
+		return true
+	case len(region) == 2:
+		return strings.EqualFold(region, country)
+	}
+	return strings.HasPrefix(zone, region+"/")
+}
+
+// observes reports whether zone is at offset at t. A zone the tz database
+// cannot load is kept rather than ruled out.
+func observes(zone string, offset time.Duration, t time.Time) bool {
+	loc, err := time.LoadLocation(zone)
+	if err != nil {
+		return true
+	}
+	_, secs := t.In(loc).Zone()
+	return time.Duration(secs)*time.Second == offset
+}
+
+var byAbbr = func() map[string][]meaning {
+	m := map[string][]meaning{}
+	for _, e := range table {
+		m[e.abbr] = append(m[e.abbr], e)
+	}
+	return m
+}()
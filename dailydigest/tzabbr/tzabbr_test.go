@@ -0,0 +1,117 @@
+package tzabbr
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestResolve(t *testing.T) {
+	january := time.Date(2026, time.January, 15, 12, 0, 0, 0, time.UTC)
+	july := time.Date(2026, time.July, 15, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		abbr      string
+		hint      Hint
+		meanings  []string
+		ambiguous bool
+		has       []string // zones that must be candidates
+		hasNot    []string // zones that must not be
+	}{
+		{"IST", "IST", Hint{},
+			[]string{"India Standard Time", "Irish Standard Time", "Israel Standard Time"}, true,
+			[]string{"Asia/Kolkata", "Europe/Dublin", "Asia/Jerusalem"}, nil},
+		{"IST in Ireland", "ist", Hint{Region: "IE"},
+			[]string{"Irish Standard Time"}, false, []string{"Europe/Dublin", "Eire"}, []string{"Asia/Kolkata"}},
+		{"IST in Asia", " IST ", Hint{Region: "Asia"},
+			[]string{"India Standard Time", "Israel Standard Time"}, true, nil, []string{"Europe/Dublin"}},
+		// Irish Standard Time is Dublin's summer offset.
+		{"IST in January", "IST", Hint{At: january},
+			[]string{"India Standard Time", "Israel Standard Time"}, true,
+			[]string{"Asia/Kolkata", "Asia/Jerusalem", "Israel"}, []string{"Europe/Dublin"}},
+		{"IST in July", "IST", Hint{At: july},
+			[]string{"India Standard Time", "Irish Standard Time"}, true,
+			[]string{"Europe/Dublin"}, []string{"Asia/Jerusalem"}},
+		{"CST", "CST", Hint{},
+			[]string{"Central Standard Time", "China Standard Time", "Taiwan Standard Time", "Cuba Standard Time"}, true,
+			[]string{"America/Chicago", "Asia/Shanghai", "America/Havana"}, nil},
+		{"CST in China", "CST", Hint{Region: "CN"},
+			[]string{"China Standard Time"}, false, []string{"Asia/Shanghai", "PRC"}, []string{"Asia/Taipei"}},
+		{"CST in the US", "CST", Hint{Region: "US"},
+			[]string{"Central Standard Time"}, false, []string{"America/Chicago"}, []string{"America/Havana"}},
+		// in July Chicago and Havana are on daylight time; Regina and
+		// Mexico City keep standard time all year.
+		{"CST in July", "CST", Hint{At: july},
+			[]string{"Central Standard Time", "China Standard Time", "Taiwan Standard Time"}, true,
+			[]string{"America/Regina", "America/Mexico_City", "Asia/Shanghai"}, []string{"America/Chicago", "America/Havana"}},
+		{"CST in America in July", "CST", Hint{Region: "America", At: july},
+			[]string{"Central Standard Time"}, false, []string{"America/Regina"}, []string{"America/Havana"}},
+		{"BST", "BST", Hint{},
+			[]string{"British Summer Time", "Bangladesh Standard Time", "Bougainville Standard Time"}, true,
+			[]string{"Europe/London", "Asia/Dhaka"}, nil},
+		{"BST in January", "BST", Hint{At: january},
+			[]string{"Bangladesh Standard Time", "Bougainville Standard Time"}, true,
+			[]string{"Asia/Dhaka", "Pacific/Bougainville"}, []string{"Europe/London", "GB", "Europe/Jersey"}},
+		{"BST in Britain in January", "BST", Hint{Region: "GB", At: january}, nil, false, nil, []string{"Europe/London"}},
+		{"BST in Britain in July", "BST", Hint{Region: "GB", At: july},
+			[]string{"British Summer Time"}, false, []string{"Europe/London", "GB"}, nil},
+		{"AST", "AST", Hint{},
+			[]string{"Atlantic Standard Time", "Arabia Standard Time"}, true,
+			[]string{"America/Halifax", "America/Puerto_Rico", "Asia/Riyadh"}, nil},
+		{"AST in Asia", "AST", Hint{Region: "Asia"},
+			[]string{"Arabia Standard Time"}, false, []string{"Asia/Riyadh", "Asia/Baghdad"}, []string{"America/Halifax"}},
+		// Halifax is on ADT in July; Puerto Rico has no DST.
+		{"AST in July", "AST", Hint{At: july},
+			[]string{"Atlantic Standard Time", "Arabia Standard Time"}, true,
+			[]string{"America/Puerto_Rico", "America/Blanc-Sablon", "Asia/Riyadh"}, []string{"America/Halifax", "Atlantic/Bermuda"}},
+		{"AST in Canada in January", "AST", Hint{Region: "CA", At: january},
+			[]string{"Atlantic Standard Time"}, false, []string{"America/Halifax", "America/Blanc-Sablon"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := Resolve(tt.abbr, tt.hint)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(r.Meanings, tt.meanings) {
+				t.Errorf("meanings = %q, want %q", r.Meanings, tt.meanings)
+			}
+			if r.Ambiguous != tt.ambiguous {
+				t.Errorf("ambiguous = %v, want %v", r.Ambiguous, tt.ambiguous)
+			}
+			var zones []string
+			for _, c := range r.Candidates {
+				zones = append(zones, c.Zone)
+			}
+			for _, z := range tt.has {
+				if !slices.Contains(zones, z) {
+					t.Errorf("%s missing from %q", z, zones)
+				}
+			}
+			for _, z := range tt.hasNot {
+				if slices.Contains(zones, z) {
+					t.Errorf("%s should not be a candidate", z)
+				}
+			}
+		})
+	}
+}
+
+func TestResolveOffsets(t *testing.T) {
+	r, err := Resolve("CST", Hint{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []time.Duration{-6 * time.Hour, -5 * time.Hour, 8 * time.Hour}
+	if got := r.Offsets(); !reflect.DeepEqual(got, want) {
+		t.Errorf("offsets = %v, want %v", got, want)
+	}
+}
+
+func TestResolveUnknown(t *testing.T) {
+	if _, err := Resolve("XYZT", Hint{}); !errors.Is(err, ErrUnknown) {
+		t.Errorf("err = %v, want ErrUnknown", err)
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// zoneinfo.zip is the tz database the lambda evaluates zones with, so a
+// runtime without zoneinfo, or with a stale copy, sees the same rules as
+// everywhere else. It is Go's lib/time/zoneinfo.zip; zoneinfo.version names
+// the tzdata release it was built from and must be updated with it.
+var (
+	//go:embed zoneinfo.zip
+	zoneinfoZip []byte
+	//go:embed zoneinfo.version
+	zoneinfoVersion string
+)
+
+// tzdataVersion is the embedded tzdata release, e.g. "2026c".
+var tzdataVersion = strings.TrimSpace(zoneinfoVersion)
+
+var zoneinfoFiles = func() map[string]*zip.File {
+	r, err := zip.NewReader(bytes.NewReader(zoneinfoZip), int64(len(zoneinfoZip)))
+	if err != nil {
+		panic(fmt.Sprintf("embedded zoneinfo.zip: %v", err))
+	}
+	m := make(map[string]*zip.File, len(r.File))
+	for _, f := range r.File {
+		m[f.Name] = f
+	}
+	return m
+}()
+
+// loadEmbeddedLocation loads name from the embedded tz database. ok is false
+// when the database has no such zone.
+func loadEmbeddedLocation(name string) (loc *time.Location, ok bool, err error) {
+	f, found := zoneinfoFiles[name]
+	if !found {
+		return nil, false, nil
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, true, err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, true, err
+	}
+	loc, err = time.LoadLocationFromTZData(name, data)
+	return loc, true, err
+}
+
+// systemZoneinfoDirs are where Linux distributions install tzdata.
+var systemZoneinfoDirs = []string{"/usr/share/zoneinfo", "/usr/share/lib/zoneinfo", "/usr/lib/locale/TZ"}
+
+// systemTZDataVersion returns the release of the system's tzdata, read from
+// tzdata.zi or +VERSION, or "" when there is none or it does not say.
+func systemTZDataVersion() string {
+	for _, dir := range systemZoneinfoDirs {
+		if data, err := os.ReadFile(filepath.Join(dir, "+VERSION")); err == nil {
+			return strings.TrimSpace(string(data))
+		}
+		data, err := os.ReadFile(filepath.Join(dir, "tzdata.zi"))
+		if err != nil {
+			continue
+		}
+		first, _, _ := strings.Cut(string(data), "\n")
+		if v, ok := strings.CutPrefix(first, "# version "); ok {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
+
+// tzdataComparison is how the system tzdata differs from the embedded copy
+// for the catalog's zones.
+type tzdataComparison struct {
+	Embedded string
+	System   string
+	// Missing are zones the system copy cannot load.
+	Missing []string
+	// Differing are zones whose UTC offset or abbreviation at now or in the
+	// coming year differs between the two.
+	Differing []string
+}
+
+func (c tzdataComparison) String() string {
+	if c.System == "" {
+		return fmt.Sprintf("embedded %s, no system tzdata", c.Embedded)
+	}
+	return fmt.Sprintf("embedded %s, system %s, %d zones missing and %d differing on the system",
+		c.Embedded, c.System, len(c.Missing), len(c.Differing))
+}
+
+// compareTZData checks every catalog zone against the system tzdata at now
+// and at the start of each of the following twelve months.
+func compareTZData(now time.Time) tzdataComparison {
+	c := tzdataComparison{Embedded: tzdataVersion, System: systemTZDataVersion()}
+	if c.System == "" {
+		return c
+	}
+	seen := map[string]bool{}
+	for _, zones := range timezones {
+		for _, tz := range zones {
+			if seen[tz] {
+				continue
+			}
+			seen[tz] = true
+			embedded, ok, err := loadEmbeddedLocation(tz)
+			if !ok || err != nil {
+				continue
+			}
+			system, err := time.LoadLocation(tz)
+			if err != nil {
+				c.Missing = append(c.Missing, tz)
+				continue
+			}
+			for i := 0; i <= 12; i++ {
+				at := now
+				if i > 0 {
+					at = time.Date(now.Year(), now.Month()+time.Month(i), 1, 12, 0, 0, 0, time.UTC)
+				}
+				en, eo := at.In(embedded).Zone()
+				sn, so := at.In(system).Zone()
+				if en != sn || eo != so {
+					c.Differing = append(c.Differing, tz)
+					break
+				}
+			}
+		}
+	}
+	return c
+}
+
+// logTZData reports at startup which tzdata the lambda runs on and, when
+// the runtime has its own copy, where that copy disagrees with it.
+func logTZData() {
+	c := compareTZData(time.Now())
+	log := baseLogger.With("tzdata_version", c.Embedded, "system_tzdata_version", c.System)
+	if len(c.Missing) > 0 || len(c.Differing) > 0 {
+		log.Warn("system tzdata differs from the embedded copy, using the embedded copy",
+			"missing", c.Missing, "differing", c.Differing)
+		return
+	}
+	log.Info("tzdata loaded", "detail", c.String())
+}
+
+func checkTZData(ctx context.Context) (string, error) {
+	return compareTZData(time.Now()).String(), nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// workWeek is when a region works: the day its week starts and the days it
+// takes off.
+type workWeek struct {
+	FirstWorkday time.Weekday
+	Weekend      []time.Weekday
+}
+
+// isWeekend reports whether d is a day off in the work week.
+func (w workWeek) isWeekend(d time.Weekday) bool {
+	for _, off := range w.Weekend {
+		if off == d {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultWorkWeek is used wherever the table has nothing else.
+var defaultWorkWeek = workWeek{FirstWorkday: time.Monday, Weekend: []time.Weekday{time.Saturday, time.Sunday}}
+
+// workWeekEra is a work week a country observed from From on; a zero From
+// means since before anything we evaluate.
+type workWeekEra struct {
+	From time.Time
+	workWeek
+}
+
+var (
+	sunThu  = workWeek{FirstWorkday: time.Sunday, Weekend: []time.Weekday{time.Friday, time.Saturday}}
+	satWed  = workWeek{FirstWorkday: time.Saturday, Weekend: []time.Weekday{time.Thursday, time.Friday}}
+	eraFrom = func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+)
+
+// builtinWorkWeeks lists, by ISO country code, the regions whose work week
+// differs from Monday to Friday, oldest era first.
+var builtinWorkWeeks = map[string][]workWeekEra{
+	"AE": {{workWeek: sunThu}, {From: eraFrom(2022, time.January, 1), workWeek: defaultWorkWeek}},
+	"SA": {{workWeek: satWed}, {From: eraFrom(2013, time.June, 29), workWeek: sunThu}},
+	"IL": {{workWeek: sunThu}},
+	"QA": {{workWeek: sunThu}},
+	"KW": {{workWeek: sunThu}},
+	"BH": {{workWeek: sunThu}},
+	"OM": {{workWeek: sunThu}},
+	"JO": {{workWeek: sunThu}},
+	"EG": {{workWeek: sunThu}},
+	"IQ": {{workWeek: sunThu}},
+	"SY": {{workWeek: sunThu}},
+	"LY": {{workWeek: sunThu}},
+	"DZ": {{workWeek: sunThu}},
+	"SD": {{workWeek: sunThu}},
+	"YE": {{workWeek: sunThu}},
+	"BD": {{workWeek: sunThu}},
+	"MV": {{workWeek: sunThu}},
+	"IR": {{workWeek: workWeek{FirstWorkday: time.Saturday, Weekend: []time.Weekday{time.Friday}}}},
+	"NP": {{workWeek: workWeek{FirstWorkday: time.Sunday, Weekend: []time.Weekday{time.Saturday}}}},
+	"BN": {{workWeek: workWeek{FirstWorkday: time.Monday, Weekend: []time.Weekday{time.Friday, time.Sunday}}}},
+}
+
+// workWeekSpec is how a work week is written in configuration:
+// {"first_workday": "sunday", "weekend": ["friday", "saturday"]}.
+type workWeekSpec struct {
+	FirstWorkday string   `json:"first_workday"`
+	Weekend      []string `json:"weekend"`
+}
+
+func (s workWeekSpec) workWeek() (workWeek, error) {
+	first, err := parseWeekday(s.FirstWorkday)
+	if err != nil {
+		return workWeek{}, err
+	}
+	w := workWeek{FirstWorkday: first}
+	for _, name := range s.Weekend {
+		d, err := parseWeekday(name)
+		if err != nil {
+			return workWeek{}, err
+		}
+		w.Weekend = append(w.Weekend, d)
+	}
+	if w.isWeekend(first) {
+		return workWeek{}, fmt.Errorf("first workday %s is a weekend day", first)
+	}
+	return w, nil
+}
+
+func parseWeekday(name string) (time.Weekday, error) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(name, d.String()) {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown weekday %q", name)
+}
+
+// workWeekOverrides maps an IANA zone, catalog abbreviation, ISO country code
+// or "*" to the work week that replaces the built-in table there.
+type workWeekOverrides map[string]workWeek
+
+func parseWorkWeeks(specs map[string]workWeekSpec) (workWeekOverrides, error) {
+	out := workWeekOverrides{}
+	for key, spec := range specs {
+		w, err := spec.workWeek()
+		if err != nil {
+			return nil, fmt.Errorf("work week for %s: %w", key, err)
+		}
+		out[key] = w
+	}
+	return out, nil
+}
+
+// loadWorkWeeks reads DIGEST_WORK_WEEKS, a secret reference to a JSON object
+// of work week overrides.
+func loadWorkWeeks(ctx context.Context, c config) (workWeekOverrides, error) {
+	if c.WorkWeeks == "" {
+		return nil, nil
+	}
+	data, err := loadSecret(ctx, c.WorkWeeks)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read work weeks: %w", err)
+	}
+	var specs map[string]workWeekSpec
+	if err := json.Unmarshal(data, &specs); err != nil {
+		return nil, fmt.Errorf("cannot decode work weeks: %w", err)
+	}
+	return parseWorkWeeks(specs)
+}
+
+func (o workWeekOverrides) lookup(group, zone string) (workWeek, bool) {
+	for _, key := range []string{zone, group, zoneCountries[zone], "*"} {
+		if w, ok := o[key]; ok && key != "" {
+			return w, true
+		}
+	}
+	return workWeek{}, false
+}
+
+// workWeekFor returns the work week in zone, found under group, on the local
+// date of day: the tenant's overrides first, then the global ones, then the
+// built-in table for the zone's country.
+func (tn *tenant) workWeekFor(group, zone string, day time.Time) workWeek {
+	if w, ok := tn.workWeeks.lookup(group, zone); ok {
+		return w
+	}
+	if w, ok := tn.globalWorkWeeks.lookup(group, zone); ok {
+		return w
+	}
+	w := defaultWorkWeek
+	date := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	for _, era := range builtinWorkWeeks[zoneCountries[zone]] {
+		if !date.Before(era.From) {
+			w = era.workWeek
+		}
+	}
+	return w
+}
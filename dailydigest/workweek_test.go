@@ -0,0 +1,75 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWorkWeekFor(t *testing.T) {
+	tn := &tenant{}
+	tests := []struct {
+		zone  string
+		date  string
+		first time.Weekday
+	}{
+		{"Europe/Paris", "2026-03-02", time.Monday},
+		{"Asia/Riyadh", "2026-03-02", time.Sunday},
+		{"Asia/Riyadh", "2013-06-01", time.Saturday},
+		{"Asia/Dubai", "2021-12-31", time.Sunday},
+		{"Asia/Dubai", "2022-01-01", time.Monday},
+		{"Asia/Jerusalem", "2026-03-02", time.Sunday},
+		{"Asia/Tehran", "2026-03-02", time.Saturday},
+		// backward-compatible names count under the zone they stand for.
+		{"Israel", "2026-03-02", time.Sunday},
+		{"Egypt", "2026-03-02", time.Sunday},
+		{"Iran", "2026-03-02", time.Saturday},
+		{"Libya", "2026-03-02", time.Sunday},
+		{"Japan", "2026-03-02", time.Monday},
+		{"Etc/GMT-3", "2026-03-02", time.Monday},
+	}
+	for _, tt := range tests {
+		if got := tn.workWeekFor("", tt.zone, date(tt.date)).FirstWorkday; got != tt.first {
+			t.Errorf("workWeekFor(%s, %s) starts on %s, want %s", tt.zone, tt.date, got, tt.first)
+		}
+	}
+}
+
+func TestCatalogZonesHaveCountries(t *testing.T) {
+	for group, zones := range timezones {
+		for _, tz := range zones {
+			if zoneCountries[tz] == "" && !countrylessZone(tz) {
+				t.Errorf("%s zone %s has no country", group, tz)
+			}
+		}
+	}
+}
+
+func TestWeeklyDay(t *testing.T) {
+	tn := &tenant{}
+	firstWorkday := schedule{CType: TypeWeeklyAt9A, Hour: 9, FirstWorkday: true}
+	for _, tt := range []struct {
+		zone  string
+		sched schedule
+		at    string // local time
+		want  bool
+	}{
+		{"Europe/Paris", weeklySchedule, "2026-03-02T09:05", true},
+		{"Asia/Riyadh", weeklySchedule, "2026-03-02T09:05", true},
+		{"Asia/Riyadh", weeklySchedule, "2026-03-01T09:05", false},
+		{"Europe/Paris", firstWorkday, "2026-03-02T09:05", true},
+		{"Europe/Paris", firstWorkday, "2026-03-01T09:05", false},
+		{"Asia/Riyadh", firstWorkday, "2026-03-01T09:05", true},
+		{"Asia/Riyadh", firstWorkday, "2026-03-02T09:05", false},
+		{"Israel", firstWorkday, "2026-03-01T09:05", true},
+	} {
+		loc, err := loadLocation(tt.zone)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tLoc, _ := time.ParseInLocation("2006-01-02T15:04", tt.at, loc)
+		onDay, _, _ := tn.due(occurrence{Zone: tt.zone, Schedule: tt.sched})(tLoc)
+		if due := tt.sched.matches(tLoc) && onDay; due != tt.want {
+			t.Errorf("%s first_workday=%v at %s: due = %v, want %v", tt.zone, tt.sched.FirstWorkday, tt.at, due, tt.want)
+		}
+	}
+}
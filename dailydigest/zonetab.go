@@ -0,0 +1,118 @@
+package main
+
+import (
+	_ "embed"
+	"strconv"
+	"strings"
+)
+
+// zone.tab is copied unchanged from the tz database; catalog.tab adds the
+// catalog zones it does not list, and backward.tab links the remaining
+// backward-compatible names to a zone that is listed.
+var (
+	//go:embed zone.tab
+	zoneTab string
+	//go:embed catalog.tab
+	catalogTab string
+	//go:embed backward.tab
+	backwardTab string
+)
+
+// zoneInfo is where a zone is: its ISO 3166 country code and the latitude
+// and longitude, in degrees, of a representative location.
+type zoneInfo struct {
+	Country string
+	Lat     float64
+	Lon     float64
+}
+
+// zoneInfos holds every zone listed in zone.tab or catalog.tab, and every
+// link in backward.tab under its target's entry.
+var zoneInfos = func() map[string]zoneInfo {
+	m := map[string]zoneInfo{}
+	for _, tab := range []string{zoneTab, catalogTab} {
+		for _, line := range strings.Split(tab, "\n") {
+			if line == "" || line[0] == '#' {
+				continue
+			}
+			f := strings.Split(line, "\t")
+			if len(f) < 3 {
+				continue
+			}
+			lat, lon, _ := parseISO6709(f[1])
+			m[f[2]] = zoneInfo{Country: f[0], Lat: lat, Lon: lon}
+		}
+	}
+	for _, line := range strings.Split(backwardTab, "\n") {
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		link, target, _ := strings.Cut(line, "\t")
+		info, ok := m[target]
+		if !ok {
+			panic("backward.tab: " + link + " links to unlisted zone " + target)
+		}
+		if _, ok := m[link]; !ok {
+			m[link] = info
+		}
+	}
+	return m
+}()
+
+// countrylessZone reports whether zone belongs to no country: the Etc zones
+// and the POSIX-style names such as UTC, CET or EST5EDT.
+func countrylessZone(zone string) bool {
+	if strings.HasPrefix(zone, "Etc/") {
+		return true
+	}
+	switch zone {
+	case "UTC", "UCT", "Universal", "Zulu", "GMT", "GMT+0", "GMT-0", "GMT0", "Greenwich",
+		"CET", "EET", "MET", "WET", "EST", "MST", "HST", "CST6CDT", "EST5EDT", "MST7MDT", "PST8PDT":
+		return true
+	}
+	return false
+}
+
+// zoneCountries maps each listed zone to its ISO 3166 country code.
+var zoneCountries = func() map[string]string {
+	m := map[string]string{}
+	for zone, info := range zoneInfos {
+		m[zone] = info.Country
+	}
+	return m
+}()
+
+// parseISO6709 parses zone.tab coordinates, +-DDMM+-DDDMM or
+// +-DDMMSS+-DDDMMSS.
+func parseISO6709(v string) (lat, lon float64, ok bool) {
+	i := strings.IndexAny(v[1:], "+-") + 1
+	if i <= 0 {
+		return 0, 0, false
+	}
+	lat, ok1 := parseDMS(v[:i], 2)
+	lon, ok2 := parseDMS(v[i:], 3)
+	return lat, lon, ok1 && ok2
+}
+
+func parseDMS(v string, degDigits int) (float64, bool) {
+	if len(v) < 1+degDigits+2 {
+		return 0, false
+	}
+	sign := 1.0
+	if v[0] == '-' {
+		sign = -1
+	}
+	digits := v[1:]
+	var parts [3]float64
+	for i, width := range []int{degDigits, 2, 2} {
+		if len(digits) == 0 {
+			break
+		}
+		n, err := strconv.Atoi(digits[:width])
+		if err != nil {
+			return 0, false
+		}
+		parts[i], digits = float64(n), digits[width:]
+	}
+	return sign * (parts[0] + parts[1]/60 + parts[2]/3600), true
+}